@@ -2,14 +2,26 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/httpcache"
 	"github.com/truemilk/ghloner/internal/logger"
 	"github.com/truemilk/ghloner/internal/repository"
+	"github.com/truemilk/ghloner/internal/repository/git"
+	"github.com/truemilk/ghloner/internal/repository/gitea"
+	repoGithub "github.com/truemilk/ghloner/internal/repository/github"
+	"github.com/truemilk/ghloner/internal/repository/gitlab"
+	"github.com/truemilk/ghloner/internal/repository/manifest"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+	"golang.org/x/oauth2"
 )
 
 func main() {
@@ -25,9 +37,50 @@ func main() {
 		os.Exit(1)
 	}
 
-	client, err := config.NewGitHubClient(cfg.Token)
+	if cfg.ListBackups {
+		if err := listBackups(cfg); err != nil {
+			slog.Error("Failed to list backups", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	tokenSource, err := config.NewTokenSource(cfg)
 	if err != nil {
-		slog.Error("Failed to create GitHub client", "error", err)
+		slog.Error("Failed to set up authentication", "error", err)
+		os.Exit(1)
+	}
+
+	// API and git HTTP traffic share the proxy and CA settings
+	httpTransport, err := config.NewHTTPTransport(cfg)
+	if err != nil {
+		slog.Error("Failed to set up HTTP transport", "error", err)
+		os.Exit(1)
+	}
+	git.InstallHTTPTransport(httpTransport)
+
+	var sshAuth *git.SSHAuth
+	if cfg.GitProtocol == config.GitProtocolSSH {
+		sshAuth, err = git.NewSSHAuth(cfg)
+		if err != nil {
+			slog.Error("Failed to set up SSH authentication", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var cache *httpcache.Cache
+	if cfg.Provider == config.ProviderGitHub && !cfg.NoHTTPCache {
+		// Installation tokens change on every run, so app entries are keyed by the app instead
+		identity := ""
+		if cfg.UseApp() {
+			identity = fmt.Sprintf("app:%d:%d:%s%s", cfg.AppID, cfg.AppInstallationID, cfg.OrgName, cfg.UserName)
+		}
+		cache = httpcache.New(cfg.StatePath("http-cache"), identity)
+	}
+
+	source, err := newSource(cfg, &http.Client{Transport: httpTransport}, tokenSource, cache)
+	if err != nil {
+		slog.Error("Failed to create repository source", "error", err)
 		os.Exit(1)
 	}
 
@@ -45,9 +98,53 @@ func main() {
 		os.Exit(1)
 	}()
 
-	processor := repository.NewProcessor(client, cfg)
+	processor := repository.NewProcessor(source, cfg)
+	processor.SetTokenSource(tokenSource)
+	if cache != nil {
+		processor.SetHTTPCache(cache)
+	}
+	if sshAuth != nil {
+		processor.SetSSHAuth(sshAuth)
+	}
 	if err := processor.Run(ctx); err != nil {
 		slog.Error("Error during processing", "error", err)
 		os.Exit(1)
 	}
 }
+
+// listBackups prints the backups of rewritten branches of every clone in the
+// output directory, one per line
+func listBackups(cfg *config.Config) error {
+	backups, err := git.ListBackups(cfg)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tBRANCH\tSAVED\tCOMMIT\tREF")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.Repository, b.Branch, b.Time.Format(time.RFC3339), b.Hash.String()[:12], b.Ref)
+	}
+	return w.Flush()
+}
+
+// newSource creates the repository source for the configured hosting provider.
+// GitLab and Gitea API calls are sent with httpClient. GitHub API calls are
+// authenticated with ts, and responses are revalidated against cache when it
+// is not nil.
+func newSource(cfg *config.Config, httpClient *http.Client, ts oauth2.TokenSource, cache *httpcache.Cache) (provider.Source, error) {
+	switch cfg.Provider {
+	case config.ProviderGitLab:
+		return gitlab.NewGroupLister(httpClient, cfg), nil
+	case config.ProviderGitea:
+		return gitea.NewOrgLister(httpClient, cfg), nil
+	case config.ProviderManifest:
+		return manifest.NewLister(cfg), nil
+	default:
+		client, err := config.NewGitHubClient(cfg, ts, cache)
+		if err != nil {
+			return nil, fmt.Errorf("error creating GitHub client: %w", err)
+		}
+		return repoGithub.NewSource(client, cfg), nil
+	}
+}
@@ -2,22 +2,159 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v60/github"
+	"github.com/truemilk/ghloner/internal/credentials"
+	"github.com/truemilk/ghloner/internal/githubapp"
+	"github.com/truemilk/ghloner/internal/httpcache"
+	"github.com/truemilk/ghloner/internal/ratelimit"
+	"golang.org/x/net/http/httpproxy"
 	"golang.org/x/oauth2"
 )
 
+// Directory layouts for cloned repositories
+const (
+	// LayoutFlat places every repository directly in the output directory
+	LayoutFlat = "flat"
+	// LayoutOwner places repositories in a directory per owner
+	LayoutOwner = "owner"
+)
+
+// StateDirName is the directory inside the output directory where ghloner
+// keeps its own state. It is never treated as a repository.
+const StateDirName = ".ghloner"
+
+// Protocols for cloning and fetching repositories
+const (
+	GitProtocolHTTPS = "https"
+	GitProtocolSSH   = "ssh"
+)
+
+// Policies for clones with local changes that an update would overwrite
+const (
+	// LocalChangesSkip leaves the clone alone and skips its update
+	LocalChangesSkip = "skip"
+	// LocalChangesStash saves the changes to a ref and updates the clone
+	LocalChangesStash = "stash"
+	// LocalChangesQuarantine moves the clone aside and clones it again
+	LocalChangesQuarantine = "quarantine"
+)
+
+// Supported hosting providers
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea"
+	// ProviderManifest reads the repositories from a local manifest file
+	ProviderManifest = "manifest"
+)
+
 type Config struct {
-	Token         string
-	OrgName       string
-	OutputDir     string
-	Workers       int
-	RetryCount    int
-	NoProgress    bool
-	ProgressStyle string
+	Provider          string
+	Manifest          string
+	Token             string
+	TokenFile         string
+	AppID             int64
+	AppInstallationID int64
+	AppPrivateKey     string
+	OrgName           string
+	OrgNames          []string
+	UserName          string
+	AuthenticatedUser bool
+	OutputDir         string
+	Workers           int
+	RetryCount        int
+	NoProgress        bool
+	ProgressStyle     string
+	Layout            string
+	APIURL            string
+	UploadURL         string
+	GitHost           string
+	GitProtocol       string
+	SSHKey            string
+	SSHKeyPassphrase  string
+	SSHKnownHosts     string
+	Proxy             string
+	CABundle          string
+	Mirror            bool
+	Depth             int
+	SingleBranch      bool
+	AllBranches       bool
+	LocalChanges      string
+	BackupRetention   time.Duration
+	ListBackups       bool
+	RepoType          string
+	Teams             []string
+	TeamPermission    string
+	NoHTTPCache       bool
+	GraphQL           bool
+
+	// Repository filters
+	IncludeNames      []string
+	ExcludeNames      []string
+	IncludeRegex      string
+	ExcludeRegex      string
+	Topics            []string
+	ExcludeTopics     []string
+	Languages         []string
+	ExcludeLanguages  []string
+	Archived          string
+	Forks             string
+	Visibility        []string
+	MinSize           int
+	MaxSize           int
+	PushedWithin      time.Duration
+	Properties        []string
+	ExcludeProperties []string
+	PruneFiltered     bool
+}
+
+// RepoTypeInternal selects internal repositories of an organization. The REST
+// API has no such type, so internal repositories are selected by visibility.
+const RepoTypeInternal = "internal"
+
+// validRepoTypes lists the repository types accepted by each GitHub listing endpoint
+var validRepoTypes = map[string][]string{
+	"organization":       {"all", "public", "private", "forks", "sources", "member", RepoTypeInternal},
+	"user":               {"all", "owner", "member"},
+	"authenticated user": {"all", "owner", "public", "private", "member"},
+}
+
+// teamPermissions lists the permission levels a team can have on a repository
+var teamPermissions = []string{"pull", "triage", "push", "maintain", "admin"}
+
+// Filter modes for archived repositories and forks
+const (
+	FilterInclude = "include"
+	FilterExclude = "exclude"
+	FilterOnly    = "only"
+)
+
+// listFlag is a flag value that collects comma-separated items, and can be repeated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, splitList(value)...)
+	return nil
 }
 
 func Parse() (*Config, error) {
@@ -26,26 +163,190 @@ func Parse() (*Config, error) {
 	cfg.Workers = 10
 	cfg.RetryCount = 5
 	cfg.ProgressStyle = "bar"
+	cfg.Provider = ProviderGitHub
+	cfg.GitProtocol = GitProtocolHTTPS
+	cfg.LocalChanges = LocalChangesSkip
+	cfg.RepoType = "all"
+	cfg.Archived = FilterInclude
+	cfg.Forks = FilterInclude
 
-	flag.StringVar(&cfg.OrgName, "org", os.Getenv("GITHUB_ORG"), "GitHub organization name")
+	flag.StringVar(&cfg.Provider, "provider", cfg.Provider, "Hosting provider (github, gitlab, gitea, manifest)")
+	flag.StringVar(&cfg.Manifest, "manifest", "", "YAML or JSON manifest listing the repositories to clone (manifest provider)")
+	flag.StringVar(&cfg.OrgName, "org", os.Getenv("GITHUB_ORG"), "GitHub or Gitea organization, or GitLab group name (comma-separated for several)")
+	flag.StringVar(&cfg.UserName, "user", os.Getenv("GITHUB_USER"), "GitHub user name (clone the user's repositories instead of an organization)")
+	flag.BoolVar(&cfg.AuthenticatedUser, "authenticated-user", false, "Clone every repository the token can access, including collaborator repositories")
 	flag.StringVar(&cfg.Token, "token", os.Getenv("GITHUB_TOKEN"), "GitHub personal access token")
+	flag.StringVar(&cfg.TokenFile, "token-file", os.Getenv("GITHUB_TOKEN_FILE"), "File containing the access token")
+	flag.Int64Var(&cfg.AppID, "app-id", 0, "GitHub App ID, to authenticate as an app installation instead of with a token")
+	flag.Int64Var(&cfg.AppInstallationID, "app-installation-id", 0, "GitHub App installation ID (looked up from the organization or user when not set)")
+	flag.StringVar(&cfg.AppPrivateKey, "app-private-key", "", "Path to the GitHub App private key PEM file")
 	flag.StringVar(&cfg.OutputDir, "output", os.Getenv("OUTPUT_DIR"), "Output directory for cloned repositories")
 	flag.IntVar(&cfg.Workers, "workers", cfg.Workers, "Number of concurrent workers")
 	flag.IntVar(&cfg.RetryCount, "retry", cfg.RetryCount, "Number of retry attempts")
 	flag.BoolVar(&cfg.NoProgress, "no-progress", false, "Disable progress bar")
 	flag.StringVar(&cfg.ProgressStyle, "progress-style", cfg.ProgressStyle, "Progress display style (bar, simple, verbose)")
+	flag.StringVar(&cfg.Layout, "layout", "", "Directory layout (flat, owner); defaults to owner when several owners are cloned")
+	flag.StringVar(&cfg.APIURL, "api-url", os.Getenv("GITHUB_API_URL"), "API base URL (for GitHub Enterprise Server, e.g. https://ghes.example.com/api/v3/, self-hosted GitLab, e.g. https://gitlab.example.com/api/v4, or Gitea, e.g. https://gitea.example.com/api/v1)")
+	flag.StringVar(&cfg.UploadURL, "upload-url", os.Getenv("GITHUB_UPLOAD_URL"), "GitHub upload URL (defaults to the API base URL)")
+	flag.StringVar(&cfg.GitHost, "git-host", os.Getenv("GITHUB_GIT_HOST"), "Host to clone from, overriding the host of the clone URLs returned by the API")
+	flag.StringVar(&cfg.GitProtocol, "git-protocol", cfg.GitProtocol, "Protocol for cloning and fetching (https, ssh)")
+	flag.StringVar(&cfg.SSHKey, "ssh-key", "", "SSH private key file; ssh-agent is used when not set (ssh protocol)")
+	flag.StringVar(&cfg.SSHKnownHosts, "ssh-known-hosts", os.Getenv("SSH_KNOWN_HOSTS"), "known_hosts file to verify SSH host keys against (defaults to ~/.ssh/known_hosts)")
+	flag.StringVar(&cfg.Proxy, "proxy", "", "Proxy URL for API and git HTTP traffic (defaults to HTTPS_PROXY and HTTP_PROXY, hosts in NO_PROXY are not proxied)")
+	flag.StringVar(&cfg.CABundle, "ca-bundle", "", "PEM file of CA certificates to trust in addition to the system roots, e.g. of a TLS-intercepting proxy")
+	flag.StringVar(&cfg.RepoType, "type", cfg.RepoType, "GitHub repository type to list (organizations: all, public, private, forks, sources, member, internal; users: all, owner, member)")
+	flag.Var((*listFlag)(&cfg.Teams), "team", "Only list repositories of these organization team slugs")
+	flag.StringVar(&cfg.TeamPermission, "team-permission", "", "Minimum team permission on listed repositories (pull, triage, push, maintain, admin)")
+	flag.Var((*listFlag)(&cfg.IncludeNames), "include", "Only clone repositories whose name matches one of these glob patterns (patterns containing / match owner/name)")
+	flag.Var((*listFlag)(&cfg.ExcludeNames), "exclude", "Skip repositories whose name matches one of these glob patterns (patterns containing / match owner/name)")
+	flag.StringVar(&cfg.IncludeRegex, "include-regex", "", "Only clone repositories whose owner/name matches this regular expression")
+	flag.StringVar(&cfg.ExcludeRegex, "exclude-regex", "", "Skip repositories whose owner/name matches this regular expression")
+	flag.Var((*listFlag)(&cfg.Topics), "topic", "Only clone repositories with at least one of these topics")
+	flag.Var((*listFlag)(&cfg.ExcludeTopics), "exclude-topic", "Skip repositories with any of these topics")
+	flag.Var((*listFlag)(&cfg.Languages), "language", "Only clone repositories whose primary language is one of these")
+	flag.Var((*listFlag)(&cfg.ExcludeLanguages), "exclude-language", "Skip repositories whose primary language is one of these")
+	flag.StringVar(&cfg.Archived, "archived", cfg.Archived, "Archived repositories (include, exclude, only)")
+	flag.StringVar(&cfg.Forks, "forks", cfg.Forks, "Forked repositories (include, exclude, only)")
+	flag.Var((*listFlag)(&cfg.Visibility), "visibility", "Only clone repositories with one of these visibilities (public, private, internal)")
+	flag.IntVar(&cfg.MinSize, "min-size", 0, "Skip repositories smaller than this size in KB")
+	flag.IntVar(&cfg.MaxSize, "max-size", 0, "Skip repositories larger than this size in KB (0 for no limit)")
+	flag.DurationVar(&cfg.PushedWithin, "pushed-within", 0, "Only clone repositories pushed within this duration, e.g. 720h (0 for no limit)")
+	flag.Var((*listFlag)(&cfg.Properties), "property", "Only clone repositories whose custom property matches, as name=value or name (any value); values of one property are alternatives")
+	flag.Var((*listFlag)(&cfg.ExcludeProperties), "exclude-property", "Skip repositories whose custom property matches, as name=value or name (any value)")
+	flag.BoolVar(&cfg.GraphQL, "graphql", false, "List GitHub repositories with the GraphQL API, which also reports default branch heads")
+	flag.BoolVar(&cfg.NoHTTPCache, "no-http-cache", false, "Disable the on-disk cache of GitHub API responses")
+	flag.BoolVar(&cfg.Mirror, "mirror", false, "Keep bare mirrors of all refs, like git clone --mirror, instead of working trees")
+	flag.IntVar(&cfg.Depth, "depth", 0, "Only clone and fetch this many commits of history (0 for the full history)")
+	flag.BoolVar(&cfg.SingleBranch, "single-branch", false, "Only clone the default branch, or the manifest branch")
+	flag.BoolVar(&cfg.AllBranches, "all-branches", false, "Create and fast-forward a local branch for every remote branch")
+	flag.StringVar(&cfg.LocalChanges, "local-changes", cfg.LocalChanges, "What to do with clones whose uncommitted changes, untracked files or local commits an update would overwrite (skip, stash, quarantine)")
+	flag.DurationVar(&cfg.BackupRetention, "backup-retention", 0, "Delete backups of rewritten branches older than this duration, e.g. 2160h (0 to keep them forever)")
+	flag.BoolVar(&cfg.ListBackups, "list-backups", false, "List the backups of rewritten branches in the output directory and exit")
+	flag.BoolVar(&cfg.PruneFiltered, "prune-filtered", false, "Delete local clones of repositories excluded by filters")
 	flag.Parse()
 
-	if cfg.OrgName == "" {
-		return nil, fmt.Errorf("org is required (via --org flag or GITHUB_ORG environment variable)")
+	cfg.OrgNames = splitList(cfg.OrgName)
+	// The passphrase is only read from the environment, so it stays out of process listings
+	cfg.SSHKeyPassphrase = os.Getenv("SSH_KEY_PASSPHRASE")
+
+	// Listing backups only reads the clones in the output directory
+	if cfg.ListBackups {
+		if cfg.OutputDir == "" {
+			return nil, fmt.Errorf("output directory is required (via --output flag or OUTPUT_DIR environment variable)")
+		}
+		return cfg, nil
+	}
+
+	switch cfg.Provider {
+	case ProviderGitHub:
+	case ProviderGitLab:
+		if cfg.UserName != "" || cfg.AuthenticatedUser {
+			return nil, fmt.Errorf("the gitlab provider only supports groups (via --org flag or GITHUB_ORG environment variable)")
+		}
+	case ProviderGitea:
+		if cfg.UserName != "" || cfg.AuthenticatedUser {
+			return nil, fmt.Errorf("the gitea provider only supports organizations (via --org flag or GITHUB_ORG environment variable)")
+		}
+		if cfg.APIURL == "" {
+			return nil, fmt.Errorf("api-url is required for the gitea provider (via --api-url flag or GITHUB_API_URL environment variable)")
+		}
+	case ProviderManifest:
+		if cfg.Manifest == "" {
+			return nil, fmt.Errorf("manifest is required for the manifest provider (via --manifest flag)")
+		}
+		if cfg.OrgName != "" || cfg.UserName != "" || cfg.AuthenticatedUser {
+			return nil, fmt.Errorf("the manifest provider cannot be combined with --org, --user or --authenticated-user")
+		}
+	default:
+		return nil, fmt.Errorf("invalid provider: %s (must be one of: github, gitlab, gitea, manifest)", cfg.Provider)
+	}
+	if cfg.Manifest != "" && cfg.Provider != ProviderManifest {
+		return nil, fmt.Errorf("manifest is only supported by the manifest provider (via --provider manifest)")
+	}
+
+	sources := 0
+	for _, set := range []bool{cfg.OrgName != "", cfg.UserName != "", cfg.AuthenticatedUser} {
+		if set {
+			sources++
+		}
 	}
-	if cfg.Token == "" {
-		return nil, fmt.Errorf("token is required (via --token flag or GITHUB_TOKEN environment variable)")
+	if sources == 0 && cfg.Provider != ProviderManifest {
+		return nil, fmt.Errorf("org is required (via --org flag or GITHUB_ORG environment variable), or use --user or --authenticated-user")
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("only one of --org, --user or --authenticated-user may be set")
+	}
+	if err := validateApp(cfg); err != nil {
+		return nil, err
+	}
+	if err := resolveToken(cfg); err != nil {
+		return nil, err
 	}
 	if cfg.OutputDir == "" {
 		return nil, fmt.Errorf("output directory is required (via --output flag or OUTPUT_DIR environment variable)")
 	}
 
+	if err := validateRepoType(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateTeams(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateGraphQL(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateGitProtocol(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateHistory(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy != "" {
+		if u, err := url.Parse(cfg.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", cfg.Proxy)
+		}
+	}
+	switch cfg.LocalChanges {
+	case LocalChangesSkip, LocalChangesStash, LocalChangesQuarantine:
+	default:
+		return nil, fmt.Errorf("invalid local changes policy: %s (must be one of: skip, stash, quarantine)", cfg.LocalChanges)
+	}
+
+	// GitLab subgroups and manifest paths are mapped to nested directories, so several owners are always possible
+	severalOwners := len(cfg.OrgNames) > 1 || cfg.AuthenticatedUser || cfg.Provider == ProviderGitLab || cfg.Provider == ProviderManifest
+	switch cfg.Layout {
+	case "":
+		cfg.Layout = LayoutFlat
+		if severalOwners {
+			cfg.Layout = LayoutOwner
+		}
+	case LayoutFlat:
+		if severalOwners {
+			return nil, fmt.Errorf("flat layout cannot be used with several owners, repository names may collide")
+		}
+	case LayoutOwner:
+	default:
+		return nil, fmt.Errorf("invalid layout: %s (must be one of: flat, owner)", cfg.Layout)
+	}
+
+	for name, mode := range map[string]string{"archived": cfg.Archived, "forks": cfg.Forks} {
+		switch mode {
+		case FilterInclude, FilterExclude, FilterOnly:
+		default:
+			return nil, fmt.Errorf("invalid %s filter: %s (must be one of: include, exclude, only)", name, mode)
+		}
+	}
+	if err := validateFilters(cfg); err != nil {
+		return nil, err
+	}
+	for _, v := range cfg.Visibility {
+		switch v {
+		case "public", "private", "internal":
+		default:
+			return nil, fmt.Errorf("invalid visibility: %s (must be one of: public, private, internal)", v)
+		}
+	}
+
 	// Validate progress style
 	validStyles := map[string]bool{"bar": true, "simple": true, "verbose": true}
 	if !validStyles[cfg.ProgressStyle] {
@@ -59,11 +360,375 @@ func Parse() (*Config, error) {
 	return cfg, nil
 }
 
-func NewGitHubClient(token string) (*github.Client, error) {
+// SourceName returns a human readable description of the configured repository source
+func (c *Config) SourceName() string {
+	switch {
+	case c.Provider == ProviderManifest:
+		return c.Manifest
+	case c.AuthenticatedUser:
+		return "authenticated user"
+	case c.UserName != "":
+		return c.UserName
+	default:
+		return strings.Join(c.OrgNames, ", ")
+	}
+}
+
+// RepositoryPath returns the path of a repository relative to the output directory
+func (c *Config) RepositoryPath(owner, name string) string {
+	if c.Layout == LayoutOwner {
+		return filepath.Join(filepath.FromSlash(owner), name)
+	}
+	return name
+}
+
+// CredentialHost returns the host to look up credentials for: the git host,
+// or the host of the API URL, or the public host of the provider
+func (c *Config) CredentialHost() string {
+	if c.GitHost != "" {
+		return c.GitHost
+	}
+	if u, err := url.Parse(c.APIURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	switch c.Provider {
+	case ProviderGitLab:
+		return "gitlab.com"
+	default:
+		return "github.com"
+	}
+}
+
+// UseApp reports whether to authenticate as a GitHub App installation
+func (c *Config) UseApp() bool {
+	return c.AppID != 0
+}
+
+// StatePath returns the path of an entry of the state directory
+func (c *Config) StatePath(elem ...string) string {
+	return filepath.Join(append([]string{c.OutputDir, StateDirName}, elem...)...)
+}
+
+// StagingDir returns the directory of this process where repositories are
+// cloned before they are moved into place. Every process has its own, so
+// overlapping runs never remove each other's clones.
+func (c *Config) StagingDir() string {
+	return c.StatePath("staging", stagingName)
+}
+
+// stagingName names the staging directory of this process as host-pid
+var stagingName = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+// validateRepoType checks the repository type against the configured source
+func validateRepoType(cfg *Config) error {
+	if cfg.Provider != ProviderGitHub {
+		if cfg.RepoType != "all" {
+			return fmt.Errorf("repository type selection is only supported by the github provider")
+		}
+		return nil
+	}
+
+	source := "organization"
+	switch {
+	case cfg.AuthenticatedUser:
+		source = "authenticated user"
+	case cfg.UserName != "":
+		source = "user"
+	}
+
+	for _, valid := range validRepoTypes[source] {
+		if cfg.RepoType == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid repository type for %s: %s (must be one of: %s)", source, cfg.RepoType, strings.Join(validRepoTypes[source], ", "))
+}
+
+// validateTeams checks the team source settings
+func validateTeams(cfg *Config) error {
+	if len(cfg.Teams) == 0 {
+		if cfg.TeamPermission != "" {
+			return fmt.Errorf("team-permission requires --team")
+		}
+		return nil
+	}
+
+	if cfg.Provider != ProviderGitHub || len(cfg.OrgNames) == 0 {
+		return fmt.Errorf("teams are only supported for GitHub organizations")
+	}
+
+	if cfg.TeamPermission == "" {
+		return nil
+	}
+	for _, permission := range teamPermissions {
+		if cfg.TeamPermission == permission {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid team permission: %s (must be one of: %s)", cfg.TeamPermission, strings.Join(teamPermissions, ", "))
+}
+
+// validateGitProtocol checks the git protocol and that SSH settings are only
+// given for the ssh protocol
+func validateGitProtocol(cfg *Config) error {
+	switch cfg.GitProtocol {
+	case GitProtocolHTTPS:
+		if cfg.SSHKey != "" {
+			return fmt.Errorf("ssh-key requires the ssh git protocol (via --git-protocol ssh)")
+		}
+	case GitProtocolSSH:
+	default:
+		return fmt.Errorf("invalid git protocol: %s (must be one of: https, ssh)", cfg.GitProtocol)
+	}
+	return nil
+}
+
+// validateHistory checks the history limits of clones. Mirrors are backups,
+// so they always keep the full history of every ref.
+func validateHistory(cfg *Config) error {
+	if cfg.Depth < 0 {
+		return fmt.Errorf("invalid depth: %d (must be 0 or more)", cfg.Depth)
+	}
+	if cfg.Mirror && (cfg.Depth > 0 || cfg.SingleBranch) {
+		return fmt.Errorf("depth and single-branch cannot be used with --mirror")
+	}
+	if cfg.AllBranches && (cfg.Mirror || cfg.SingleBranch) {
+		return fmt.Errorf("all-branches cannot be used with --mirror or --single-branch")
+	}
+	if cfg.BackupRetention < 0 {
+		return fmt.Errorf("invalid backup retention: %s (must be 0 or more)", cfg.BackupRetention)
+	}
+	return nil
+}
+
+// validateFilters rejects metadata filters for providers that do not report
+// the metadata they read. Every repository would be filtered out, and deleted
+// with --prune-filtered.
+func validateFilters(cfg *Config) error {
+	filters := []struct {
+		name      string
+		set       bool
+		providers []string
+	}{
+		{"topic", len(cfg.Topics) > 0 || len(cfg.ExcludeTopics) > 0, []string{ProviderGitHub, ProviderGitLab}},
+		{"language", len(cfg.Languages) > 0 || len(cfg.ExcludeLanguages) > 0, []string{ProviderGitHub, ProviderGitea}},
+		{"size", cfg.MinSize > 0 || cfg.MaxSize > 0, []string{ProviderGitHub, ProviderGitea}},
+		{"pushed-within", cfg.PushedWithin > 0, []string{ProviderGitHub, ProviderGitLab}},
+		{"visibility", len(cfg.Visibility) > 0, []string{ProviderGitHub, ProviderGitLab, ProviderGitea}},
+		{"archived", cfg.Archived != FilterInclude, []string{ProviderGitHub, ProviderGitLab, ProviderGitea}},
+		{"forks", cfg.Forks != FilterInclude, []string{ProviderGitHub, ProviderGitLab, ProviderGitea}},
+	}
+	for _, f := range filters {
+		if f.set && !slices.Contains(f.providers, cfg.Provider) {
+			return fmt.Errorf("%s filters are not supported by the %s provider (supported by: %s)", f.name, cfg.Provider, strings.Join(f.providers, ", "))
+		}
+	}
+	return nil
+}
+
+// resolveToken reads the token from the token file, or looks it up from git
+// credential helpers, the gh CLI configuration or .netrc when no token was given
+func resolveToken(cfg *Config) error {
+	if cfg.TokenFile != "" {
+		if cfg.Token != "" {
+			return fmt.Errorf("only one of --token or --token-file may be set")
+		}
+		token, err := credentials.ReadTokenFile(cfg.TokenFile)
+		if err != nil {
+			return err
+		}
+		cfg.Token = token
+		slog.Info("Using token", "source", "token file", "path", cfg.TokenFile)
+		return nil
+	}
+
+	if cfg.Token != "" {
+		slog.Info("Using token", "source", "--token flag or GITHUB_TOKEN")
+		return nil
+	}
+	// Manifest repositories may be public or local, so the token is optional
+	if cfg.UseApp() || cfg.Provider == ProviderManifest {
+		return nil
+	}
+
+	token, source, err := credentials.NewResolver().Resolve(context.Background(), cfg.CredentialHost())
+	if err != nil {
+		return fmt.Errorf("token is required (via --token flag, --token-file or GITHUB_TOKEN environment variable): %w", err)
+	}
+	cfg.Token = token
+	slog.Info("Using token", "source", source, "host", cfg.CredentialHost())
+	return nil
+}
+
+// validateApp checks the GitHub App authentication settings. An installation
+// token only grants access to the account the app is installed on.
+func validateApp(cfg *Config) error {
+	if cfg.AppID == 0 && cfg.AppPrivateKey == "" && cfg.AppInstallationID == 0 {
+		return nil
+	}
+	if cfg.AppID == 0 || cfg.AppPrivateKey == "" {
+		return fmt.Errorf("app-id and app-private-key are both required for GitHub App authentication")
+	}
+	if cfg.Provider != ProviderGitHub {
+		return fmt.Errorf("GitHub App authentication is only supported by the github provider")
+	}
+	if cfg.Token != "" || cfg.TokenFile != "" {
+		return fmt.Errorf("only one of --token or GitHub App authentication may be set")
+	}
+	if cfg.AuthenticatedUser {
+		return fmt.Errorf("GitHub App authentication cannot be used with --authenticated-user")
+	}
+	if len(cfg.OrgNames) > 1 {
+		return fmt.Errorf("GitHub App authentication supports a single organization")
+	}
+	return nil
+}
+
+// validateGraphQL checks that the GraphQL lister supports the configured source
+func validateGraphQL(cfg *Config) error {
+	if !cfg.GraphQL {
+		return nil
+	}
+	if cfg.Provider != ProviderGitHub {
+		return fmt.Errorf("graphql listing is only supported by the github provider")
+	}
+	if len(cfg.Teams) > 0 {
+		return fmt.Errorf("graphql listing cannot be combined with --team")
+	}
+	if cfg.RepoType != "all" {
+		return fmt.Errorf("graphql listing does not support repository types, use the visibility and forks filters instead")
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// NewTokenSource returns the source of the token used for API calls and git
+// operations. GitHub App installation tokens are refreshed before they expire.
+func NewTokenSource(cfg *Config) (oauth2.TokenSource, error) {
+	if !cfg.UseApp() {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.Token}), nil
+	}
+
+	key, err := githubapp.LoadPrivateKey(cfg.AppPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := NewHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := githubapp.NewTransport(cfg.AppID, key, ratelimit.NewTransport(base, cfg.RetryCount))
+	appClient, err := withEnterpriseURLs(cfg, github.NewClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, err
+	}
+
+	orgName := ""
+	if len(cfg.OrgNames) > 0 {
+		orgName = cfg.OrgNames[0]
+	}
+	return githubapp.NewTokenSource(appClient, cfg.AppInstallationID, orgName, cfg.UserName), nil
+}
+
+// NewGitHubClient creates a GitHub API client authenticated with ts, or with
+// the configured token when ts is nil. Responses are revalidated against cache
+// when it is not nil.
+func NewGitHubClient(cfg *Config, ts oauth2.TokenSource, cache *httpcache.Cache) (*github.Client, error) {
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
+	if ts == nil {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: cfg.Token},
+		)
+	}
+	base, err := NewHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Rate limited requests wait for the limit to reset instead of failing
+	var transport http.RoundTripper = ratelimit.NewTransport(base, cfg.RetryCount)
+	if cache != nil {
+		transport = cache.Transport(transport)
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
 	tc := oauth2.NewClient(ctx, ts)
-	return github.NewClient(tc), nil
+	return withEnterpriseURLs(cfg, github.NewClient(tc))
+}
+
+// NewHTTPTransport creates the HTTP transport of API and git traffic. Requests
+// go through the configured proxy, or the proxy of the HTTPS_PROXY and
+// HTTP_PROXY environment variables, except for hosts listed in NO_PROXY.
+// Certificates of the CA bundle are trusted in addition to the system roots.
+func NewHTTPTransport(cfg *Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxy := (&httpproxy.Config{
+			HTTPProxy:  cfg.Proxy,
+			HTTPSProxy: cfg.Proxy,
+			NoProxy:    firstEnv("NO_PROXY", "no_proxy"),
+		}).ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+
+	if cfg.CABundle != "" {
+		data, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return transport, nil
+}
+
+// firstEnv returns the value of the first set environment variable
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// withEnterpriseURLs points the client at the configured GitHub Enterprise
+// Server, if any
+func withEnterpriseURLs(cfg *Config, client *github.Client) (*github.Client, error) {
+	if cfg.APIURL == "" {
+		return client, nil
+	}
+
+	uploadURL := cfg.UploadURL
+	if uploadURL == "" {
+		uploadURL = cfg.APIURL
+	}
+
+	client, err := client.WithEnterpriseURLs(cfg.APIURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring GitHub Enterprise URLs: %w", err)
+	}
+	return client, nil
 }
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"encoding/pem"
 	"flag"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -101,6 +105,317 @@ func TestParse(t *testing.T) {
 			wantErr: true,
 			errContains: "org is required",
 		},
+		{
+			name:    "user source instead of organization",
+			args:    []string{"-user", "someone", "-token", "test-token", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				UserName:   "someone",
+				Token:      "test-token",
+				OutputDir:  "./repos",
+				Workers:    10,
+				RetryCount: 5,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "authenticated user source",
+			args:    []string{"-authenticated-user", "-token", "test-token", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				AuthenticatedUser: true,
+				Token:             "test-token",
+				OutputDir:         "./repos",
+				Workers:           10,
+				RetryCount:        5,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "organization and user are mutually exclusive",
+			args:        []string{"-org", "testorg", "-user", "someone", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "only one of --org, --user or --authenticated-user",
+		},
+		{
+			name:    "several organizations use owner layout",
+			args:    []string{"-org", "orga, orgb", "-token", "test-token", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				OrgName:    "orga, orgb",
+				OrgNames:   []string{"orga", "orgb"},
+				Token:      "test-token",
+				OutputDir:  "./repos",
+				Workers:    10,
+				RetryCount: 5,
+				Layout:     LayoutOwner,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "flat layout rejected for several organizations",
+			args:        []string{"-org", "orga,orgb", "-layout", "flat", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "flat layout cannot be used with several owners",
+		},
+		{
+			name:        "invalid layout",
+			args:        []string{"-org", "testorg", "-layout", "nested", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid layout",
+		},
+		{
+			name:    "gitlab provider uses owner layout",
+			args:    []string{"-provider", "gitlab", "-org", "platform", "-token", "test-token", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				Provider:   ProviderGitLab,
+				OrgName:    "platform",
+				Token:      "test-token",
+				OutputDir:  "./repos",
+				Workers:    10,
+				RetryCount: 5,
+				Layout:     LayoutOwner,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "gitlab provider rejects user source",
+			args:        []string{"-provider", "gitlab", "-user", "someone", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "only supports groups",
+		},
+		{
+			name:        "gitea provider requires api url",
+			args:        []string{"-provider", "gitea", "-org", "tools", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "api-url is required for the gitea provider",
+		},
+		{
+			name:    "gitea provider",
+			args:    []string{"-provider", "gitea", "-org", "tools", "-api-url", "https://forgejo.example.com/api/v1", "-token", "test-token", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				Provider:   ProviderGitea,
+				OrgName:    "tools",
+				Token:      "test-token",
+				OutputDir:  "./repos",
+				Workers:    10,
+				RetryCount: 5,
+				Layout:     LayoutFlat,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "manifest provider without token",
+			args:    []string{"-provider", "manifest", "-manifest", "repos.yaml", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				Provider:   ProviderManifest,
+				Manifest:   "repos.yaml",
+				OutputDir:  "./repos",
+				Workers:    10,
+				RetryCount: 5,
+				Layout:     LayoutOwner,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "manifest provider requires manifest",
+			args:        []string{"-provider", "manifest", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "manifest is required for the manifest provider",
+		},
+		{
+			name:        "manifest provider with organization",
+			args:        []string{"-provider", "manifest", "-manifest", "repos.yaml", "-org", "testorg", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "cannot be combined with --org",
+		},
+		{
+			name:        "manifest without manifest provider",
+			args:        []string{"-org", "testorg", "-manifest", "repos.yaml", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "manifest is only supported by the manifest provider",
+		},
+		{
+			name:        "invalid provider",
+			args:        []string{"-provider", "svn", "-org", "testorg", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid provider",
+		},
+		{
+			name: "repeated and comma-separated filter lists",
+			args: []string{"-org", "testorg", "-token", "test-token", "-output", "./repos",
+				"-include", "api-*,web-*", "-include", "lib-*", "-archived", "exclude"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				OrgName:      "testorg",
+				Token:        "test-token",
+				OutputDir:    "./repos",
+				Workers:      10,
+				RetryCount:   5,
+				IncludeNames: []string{"api-*", "web-*", "lib-*"},
+				Archived:     FilterExclude,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "invalid archived filter",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-archived", "sometimes"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid archived filter",
+		},
+		{
+			name:        "invalid visibility filter",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-visibility", "secret"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid visibility",
+		},
+		{
+			name:        "pushed-within filter rejected for gitea",
+			args:        []string{"-provider", "gitea", "-org", "tools", "-api-url", "https://forgejo.example.com/api/v1", "-token", "test-token", "-output", "./repos", "-pushed-within", "720h"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "pushed-within filters are not supported by the gitea provider",
+		},
+		{
+			name:        "size filter rejected for gitlab",
+			args:        []string{"-provider", "gitlab", "-org", "platform", "-token", "test-token", "-output", "./repos", "-min-size", "100"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "size filters are not supported by the gitlab provider",
+		},
+		{
+			name:        "language filter rejected for manifest",
+			args:        []string{"-provider", "manifest", "-manifest", "repos.yaml", "-output", "./repos", "-language", "go"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "language filters are not supported by the manifest provider",
+		},
+		{
+			name:        "archived filter rejected for manifest",
+			args:        []string{"-provider", "manifest", "-manifest", "repos.yaml", "-output", "./repos", "-archived", "only"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "archived filters are not supported by the manifest provider",
+		},
+		{
+			name:        "forks filter rejected for manifest",
+			args:        []string{"-provider", "manifest", "-manifest", "repos.yaml", "-output", "./repos", "-forks", "exclude"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "forks filters are not supported by the manifest provider",
+		},
+		{
+			name:        "invalid repository type for organization",
+			args:        []string{"-org", "testorg", "-type", "owner", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid repository type for organization: owner",
+		},
+		{
+			name:        "repository type for user",
+			args:        []string{"-user", "someone", "-type", "internal", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid repository type for user: internal",
+		},
+		{
+			name:        "repository type requires github provider",
+			args:        []string{"-provider", "gitlab", "-org", "platform", "-type", "private", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "only supported by the github provider",
+		},
+		{
+			name:        "teams require an organization",
+			args:        []string{"-user", "someone", "-team", "backend", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "teams are only supported for GitHub organizations",
+		},
+		{
+			name:        "invalid team permission",
+			args:        []string{"-org", "testorg", "-team", "backend", "-team-permission", "owner", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid team permission",
+		},
+		{
+			name:        "team permission requires team",
+			args:        []string{"-org", "testorg", "-team-permission", "push", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "team-permission requires --team",
+		},
+		{
+			name:        "graphql with teams",
+			args:        []string{"-org", "testorg", "-graphql", "-team", "backend", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "graphql listing cannot be combined with --team",
+		},
+		{
+			name:        "graphql with repository type",
+			args:        []string{"-org", "testorg", "-graphql", "-type", "sources", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "graphql listing does not support repository types",
+		},
+		{
+			name:        "graphql with gitlab provider",
+			args:        []string{"-provider", "gitlab", "-org", "platform", "-graphql", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "graphql listing is only supported by the github provider",
+		},
+		{
+			name:    "github app authentication without token",
+			args:    []string{"-org", "testorg", "-app-id", "7", "-app-private-key", "app.pem", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				OrgName:       "testorg",
+				AppID:         7,
+				AppPrivateKey: "app.pem",
+				OutputDir:     "./repos",
+				Workers:       10,
+				RetryCount:    5,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "github app requires private key",
+			args:        []string{"-org", "testorg", "-app-id", "7", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "app-id and app-private-key are both required",
+		},
+		{
+			name:        "github app with token",
+			args:        []string{"-org", "testorg", "-app-id", "7", "-app-private-key", "app.pem", "-token", "test-token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "only one of --token or GitHub App authentication",
+		},
+		{
+			name:        "github app with several organizations",
+			args:        []string{"-org", "org1,org2", "-app-id", "7", "-app-private-key", "app.pem", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "GitHub App authentication supports a single organization",
+		},
 		{
 			name:    "missing required token",
 			args:    []string{"-org", "testorg", "-output", "./repos"},
@@ -108,6 +423,94 @@ func TestParse(t *testing.T) {
 			wantErr: true,
 			errContains: "token is required",
 		},
+		{
+			name:        "invalid git protocol",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-git-protocol", "git"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid git protocol: git",
+		},
+		{
+			name:        "ssh key requires the ssh protocol",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-ssh-key", "id_ed25519"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "ssh-key requires the ssh git protocol",
+		},
+		{
+			name:        "negative depth",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-depth", "-1"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid depth: -1",
+		},
+		{
+			name:        "shallow mirrors",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-mirror", "-depth", "1"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "depth and single-branch cannot be used with --mirror",
+		},
+		{
+			name:        "all branches of a single branch clone",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-single-branch", "-all-branches"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "all-branches cannot be used with --mirror or --single-branch",
+		},
+		{
+			name:        "invalid local changes policy",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-local-changes", "discard"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid local changes policy: discard",
+		},
+		{
+			name:        "negative backup retention",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-backup-retention", "-1h"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid backup retention: -1h0m0s",
+		},
+		{
+			name:    "list backups without org or token",
+			args:    []string{"-list-backups", "-output", "./repos"},
+			envVars: map[string]string{},
+			wantConfig: Config{
+				OutputDir:   "./repos",
+				Workers:     10,
+				RetryCount:  5,
+				ListBackups: true,
+			},
+		},
+		{
+			name:        "list backups requires an output directory",
+			args:        []string{"-list-backups"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "output directory is required",
+		},
+		{
+			name:        "invalid proxy URL",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-output", "./repos", "-proxy", "proxy.example.com"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "invalid proxy URL",
+		},
+		{
+			name:        "token and token file are mutually exclusive",
+			args:        []string{"-org", "testorg", "-token", "test-token", "-token-file", "token", "-output", "./repos"},
+			envVars:     map[string]string{},
+			wantErr:     true,
+			errContains: "only one of --token or --token-file may be set",
+		},
+		{
+			name:        "missing token file",
+			args:        []string{"-org", "testorg", "-output", "./repos"},
+			envVars:     map[string]string{"GITHUB_TOKEN_FILE": "missing-token"},
+			wantErr:     true,
+			errContains: "error reading token file",
+		},
 		{
 			name:    "missing required output",
 			args:    []string{"-org", "testorg", "-token", "test-token"},
@@ -197,6 +600,31 @@ func TestParse(t *testing.T) {
 
 			require.NoError(t, err)
 			assert.Equal(t, tt.wantConfig.OrgName, cfg.OrgName)
+			assert.Equal(t, tt.wantConfig.UserName, cfg.UserName)
+			if tt.wantConfig.OrgNames != nil {
+				assert.Equal(t, tt.wantConfig.OrgNames, cfg.OrgNames)
+			}
+			if tt.wantConfig.Provider != "" {
+				assert.Equal(t, tt.wantConfig.Provider, cfg.Provider)
+			}
+			if tt.wantConfig.AppID != 0 {
+				assert.Equal(t, tt.wantConfig.AppID, cfg.AppID)
+				assert.Equal(t, tt.wantConfig.AppPrivateKey, cfg.AppPrivateKey)
+			}
+			assert.Equal(t, tt.wantConfig.ListBackups, cfg.ListBackups)
+			if tt.wantConfig.Manifest != "" {
+				assert.Equal(t, tt.wantConfig.Manifest, cfg.Manifest)
+			}
+			if tt.wantConfig.IncludeNames != nil {
+				assert.Equal(t, tt.wantConfig.IncludeNames, cfg.IncludeNames)
+			}
+			if tt.wantConfig.Archived != "" {
+				assert.Equal(t, tt.wantConfig.Archived, cfg.Archived)
+			}
+			if tt.wantConfig.Layout != "" {
+				assert.Equal(t, tt.wantConfig.Layout, cfg.Layout)
+			}
+			assert.Equal(t, tt.wantConfig.AuthenticatedUser, cfg.AuthenticatedUser)
 			assert.Equal(t, tt.wantConfig.Token, cfg.Token)
 			assert.Equal(t, tt.wantConfig.OutputDir, cfg.OutputDir)
 			assert.Equal(t, tt.wantConfig.Workers, cfg.Workers)
@@ -205,12 +633,139 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestResolveToken(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+	netrc := filepath.Join(dir, "netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte("machine ghes.example.com login someone password netrc-token\n"), 0600))
+
+	// Keep the user's own credentials out of the lookup
+	t.Setenv("HOME", dir)
+	t.Setenv("PATH", "")
+	t.Setenv("GH_CONFIG_DIR", dir)
+	t.Setenv("NETRC", netrc)
+
+	cfg := &Config{TokenFile: tokenFile}
+	require.NoError(t, resolveToken(cfg))
+	assert.Equal(t, "file-token", cfg.Token)
+
+	cfg = &Config{GitHost: "ghes.example.com"}
+	require.NoError(t, resolveToken(cfg))
+	assert.Equal(t, "netrc-token", cfg.Token)
+
+	cfg = &Config{}
+	err := resolveToken(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no token found for github.com (tried: git credential helper, gh CLI config, .netrc)")
+}
+
+func TestNewHTTPTransport_Proxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "ghes.example.com")
+
+	transport, err := NewHTTPTransport(&Config{Proxy: "http://proxy.example.com:3128"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.github.com/orgs/testorg/repos", nil)
+	proxyURL, err := transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxyURL.String())
+
+	// Hosts in NO_PROXY are reached directly
+	req = httptest.NewRequest(http.MethodGet, "https://ghes.example.com/api/v3/orgs/testorg/repos", nil)
+	proxyURL, err = transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Nil(t, proxyURL)
+}
+
+func TestNewHTTPTransport_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server certificate is not trusted by default
+	transport, err := NewHTTPTransport(&Config{})
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	require.Error(t, err)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	transport, err = NewHTTPTransport(&Config{CABundle: bundle})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a certificate"), 0600))
+	_, err = NewHTTPTransport(&Config{CABundle: invalid})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no certificates found in CA bundle")
+}
+
+func TestRepositoryPath(t *testing.T) {
+	flat := &Config{Layout: LayoutFlat}
+	assert.Equal(t, "repo", flat.RepositoryPath("owner", "repo"))
+
+	owner := &Config{Layout: LayoutOwner}
+	assert.Equal(t, filepath.Join("owner", "repo"), owner.RepositoryPath("owner", "repo"))
+}
+
 func TestNewGitHubClient(t *testing.T) {
-	token := "test-token"
+	cfg := &Config{Token: "test-token"}
 
-	client, err := NewGitHubClient(token)
+	client, err := NewGitHubClient(cfg, nil, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, client)
+	assert.Equal(t, "https://api.github.com/", client.BaseURL.String())
+}
+
+func TestNewTokenSource(t *testing.T) {
+	ts, err := NewTokenSource(&Config{Token: "test-token"})
+	require.NoError(t, err)
+	token, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "test-token", token.AccessToken)
+
+	_, err = NewTokenSource(&Config{AppID: 7, AppPrivateKey: filepath.Join(t.TempDir(), "missing.pem")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error reading app private key")
+}
+
+func TestNewGitHubClient_Enterprise(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiURL     string
+		uploadURL  string
+		wantBase   string
+		wantUpload string
+	}{
+		{
+			name:       "api url only",
+			apiURL:     "https://ghes.example.com",
+			wantBase:   "https://ghes.example.com/api/v3/",
+			wantUpload: "https://ghes.example.com/api/uploads/",
+		},
+		{
+			name:       "api and upload urls",
+			apiURL:     "https://ghes.example.com/api/v3/",
+			uploadURL:  "https://uploads.example.com/api/uploads/",
+			wantBase:   "https://ghes.example.com/api/v3/",
+			wantUpload: "https://uploads.example.com/api/uploads/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Token: "test-token", APIURL: tt.apiURL, UploadURL: tt.uploadURL}
+
+			client, err := NewGitHubClient(cfg, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBase, client.BaseURL.String())
+			assert.Equal(t, tt.wantUpload, client.UploadURL.String())
+		})
+	}
 }
 
 // Helper function to split environment variable
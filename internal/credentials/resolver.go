@@ -0,0 +1,266 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitCredentialTimeout bounds a credential helper, which might otherwise wait
+// for input that never comes
+const gitCredentialTimeout = 10 * time.Second
+
+// lookup finds a token for a host. It returns an empty token when the source
+// has none.
+type lookup func(ctx context.Context, host string) (string, error)
+
+// source is a named place a token can come from
+type source struct {
+	name   string
+	lookup lookup
+}
+
+// NotFoundError is returned when no source has a token for the host
+type NotFoundError struct {
+	Host  string
+	Tried []string
+}
+
+// Error implements the error interface
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no token found for %s (tried: %s)", e.Host, strings.Join(e.Tried, ", "))
+}
+
+// Resolver looks up a token from a chain of credential sources, in order
+type Resolver struct {
+	sources []source
+}
+
+// NewResolver creates a resolver that tries git credential helpers, the gh
+// CLI configuration and .netrc, in that order
+func NewResolver() *Resolver {
+	return &Resolver{
+		sources: []source{
+			{name: "git credential helper", lookup: gitCredential},
+			{name: "gh CLI config", lookup: ghHostsToken},
+			{name: ".netrc", lookup: netrcToken},
+		},
+	}
+}
+
+// Resolve returns the first token found for host and the name of its source
+func (r *Resolver) Resolve(ctx context.Context, host string) (string, string, error) {
+	tried := make([]string, 0, len(r.sources))
+	for _, s := range r.sources {
+		tried = append(tried, s.name)
+
+		token, err := s.lookup(ctx, host)
+		if err != nil {
+			slog.Warn("Failed to read credentials", "source", s.name, "host", host, "error", err)
+			continue
+		}
+		if token != "" {
+			return token, s.name, nil
+		}
+	}
+	return "", "", &NotFoundError{Host: host, Tried: tried}
+}
+
+// ReadTokenFile reads a token from a file, ignoring surrounding whitespace
+func ReadTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// gitCredential asks the configured git credential helpers for the password of
+// host. Prompts are disabled, so a missing credential fails instead of blocking.
+func gitCredential(ctx context.Context, host string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitCredentialTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\n\n", host))
+	cmd.Env = append(withoutAskPass(os.Environ()), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		// Without a matching credential, git fails trying to prompt for one
+		slog.Debug("git credential fill found no credential", "host", host, "error", err)
+		return "", nil
+	}
+
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if password, ok := strings.CutPrefix(scanner.Text(), "password="); ok {
+			return password, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// withoutAskPass drops askpass programs from the environment, which could
+// otherwise open a password dialog
+func withoutAskPass(env []string) []string {
+	result := make([]string, 0, len(env))
+	for _, v := range env {
+		if strings.HasPrefix(v, "GIT_ASKPASS=") || strings.HasPrefix(v, "SSH_ASKPASS=") {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// ghHost is the entry of a host in the gh CLI hosts.yml
+type ghHost struct {
+	OAuthToken string `yaml:"oauth_token"`
+	User       string `yaml:"user"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// ghHostsToken reads the token of host from the gh CLI hosts.yml. Recent gh
+// versions keep tokens in the system keyring instead, which is not read.
+func ghHostsToken(ctx context.Context, host string) (string, error) {
+	path := ghHostsPath()
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("error parsing gh hosts.yml: %w", err)
+	}
+
+	entry, ok := hosts[host]
+	if !ok {
+		return "", nil
+	}
+	if entry.OAuthToken != "" {
+		return entry.OAuthToken, nil
+	}
+	return entry.Users[entry.User].OAuthToken, nil
+}
+
+// ghHostsPath returns the location of the gh CLI hosts.yml, or an empty path
+// when there is no home directory
+func ghHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// netrcToken reads the password of host from the .netrc file, falling back
+// to its default entry
+func netrcToken(ctx context.Context, host string) (string, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return parseNetrc(string(data), host), nil
+}
+
+// parseNetrc returns the password of the machine entry for host, or of the
+// default entry when there is none
+func parseNetrc(data, host string) string {
+	var password, defaultPassword, machine string
+	inDefault, inMacro := false, false
+
+	for _, line := range strings.Split(data, "\n") {
+		// Macro definitions end at the next blank line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			switch fields[i] {
+			case "machine":
+				machine, inDefault = "", false
+				if i+1 < len(fields) {
+					i++
+					machine = fields[i]
+				}
+			case "default":
+				machine, inDefault = "", true
+			case "login", "account":
+				i++
+			case "password":
+				if i+1 >= len(fields) {
+					continue
+				}
+				i++
+				if machine == host && password == "" {
+					password = fields[i]
+				} else if inDefault && defaultPassword == "" {
+					defaultPassword = fields[i]
+				}
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	return firstNonEmpty(password, defaultPassword)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
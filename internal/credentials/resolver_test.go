@@ -0,0 +1,131 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	none := func(ctx context.Context, host string) (string, error) { return "", nil }
+	broken := func(ctx context.Context, host string) (string, error) { return "", errors.New("unreadable") }
+	found := func(ctx context.Context, host string) (string, error) { return "token-for-" + host, nil }
+
+	resolver := &Resolver{sources: []source{
+		{name: "first", lookup: none},
+		{name: "second", lookup: broken},
+		{name: "third", lookup: found},
+	}}
+	token, name, err := resolver.Resolve(context.Background(), "github.com")
+	require.NoError(t, err)
+	assert.Equal(t, "token-for-github.com", token)
+	assert.Equal(t, "third", name)
+
+	resolver = &Resolver{sources: []source{
+		{name: "first", lookup: none},
+		{name: "second", lookup: broken},
+	}}
+	_, _, err = resolver.Resolve(context.Background(), "github.com")
+	var notFound *NotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "no token found for github.com (tried: first, second)", err.Error())
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(path, []byte("  ghp_secret\n"), 0600))
+
+	token, err := ReadTokenFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_secret", token)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("\n"), 0600))
+	_, err = ReadTokenFile(empty)
+	assert.ErrorContains(t, err, "is empty")
+
+	_, err = ReadTokenFile(filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, err, "error reading token file")
+}
+
+func TestGitCredential(t *testing.T) {
+	home := t.TempDir()
+	gitConfig := filepath.Join(home, "gitconfig")
+	require.NoError(t, os.WriteFile(gitConfig, []byte(`[credential "https://ghes.example.com"]
+	helper = "!f() { echo username=x-access-token; echo password=helper-secret; }; f"
+`), 0600))
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", gitConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	token, err := gitCredential(context.Background(), "ghes.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "helper-secret", token)
+
+	// Hosts without a helper fail to prompt, which is not an error
+	token, err = gitCredential(context.Background(), "github.com")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestGHHostsToken(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", dir)
+
+	token, err := ghHostsToken(context.Background(), "github.com")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(`github.com:
+    oauth_token: gho_legacy
+    user: someone
+    git_protocol: https
+ghes.example.com:
+    user: someone
+    users:
+        someone:
+            oauth_token: gho_multi
+`), 0600))
+
+	token, err = ghHostsToken(context.Background(), "github.com")
+	require.NoError(t, err)
+	assert.Equal(t, "gho_legacy", token)
+
+	token, err = ghHostsToken(context.Background(), "ghes.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "gho_multi", token)
+}
+
+func TestParseNetrc(t *testing.T) {
+	netrc := `machine gitlab.com login someone password glpat-gitlab
+
+macdef init
+machine github.com password not-a-credential
+
+machine github.com
+	login someone
+	password ghp_netrc
+default login anonymous password fallback
+`
+
+	assert.Equal(t, "ghp_netrc", parseNetrc(netrc, "github.com"))
+	assert.Equal(t, "glpat-gitlab", parseNetrc(netrc, "gitlab.com"))
+	assert.Equal(t, "fallback", parseNetrc(netrc, "ghes.example.com"))
+	assert.Empty(t, parseNetrc("machine gitlab.com password x", "github.com"))
+}
+
+func TestNetrcToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	require.NoError(t, os.WriteFile(path, []byte("machine github.com login someone password ghp_netrc\n"), 0600))
+	t.Setenv("NETRC", path)
+
+	token, err := netrcToken(context.Background(), "github.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_netrc", token)
+}
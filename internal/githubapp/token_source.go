@@ -0,0 +1,186 @@
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"golang.org/x/oauth2"
+)
+
+// RefreshMargin is how long before expiry an installation token is replaced.
+// Installation tokens are valid for one hour.
+const RefreshMargin = 5 * time.Minute
+
+// jwtLifetime is the lifetime of an app JWT. GitHub accepts at most ten
+// minutes, and the issue time is backdated to allow for clock drift.
+const jwtLifetime = 9 * time.Minute
+
+// LoadPrivateKey reads a GitHub App private key from a PEM file in PKCS#1 or
+// PKCS#8 format
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading app private key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("error parsing app private key %s: no PEM data found", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing app private key %s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("error parsing app private key %s: not an RSA key", path)
+	}
+	return key, nil
+}
+
+// NewJWT returns an RS256 signed JWT that authenticates as the app
+func NewJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing app JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Transport is an http.RoundTripper that authenticates requests as the app.
+// A fresh JWT is signed for every request.
+type Transport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+	now   func() time.Time
+}
+
+// NewTransport creates a transport that authenticates as the app
+func NewTransport(appID int64, key *rsa.PrivateKey, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		appID: appID,
+		key:   key,
+		base:  base,
+		now:   time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := NewJWT(t.appID, t.key, t.now())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// installationTokenSource exchanges the app JWT for installation tokens
+type installationTokenSource struct {
+	client         *github.Client
+	orgName        string
+	userName       string
+	mu             sync.Mutex
+	installationID int64
+}
+
+// NewTokenSource returns a token source of installation access tokens. The
+// client must authenticate as the app, see NewTransport. When installationID
+// is zero, the installation is looked up from the organization or user name.
+// Tokens are reused until they are about to expire.
+func NewTokenSource(client *github.Client, installationID int64, orgName, userName string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSourceWithExpiry(nil, &installationTokenSource{
+		client:         client,
+		orgName:        orgName,
+		userName:       userName,
+		installationID: installationID,
+	}, RefreshMargin)
+}
+
+// Token implements oauth2.TokenSource
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.Background()
+
+	installationID, err := s.installation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := s.client.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating installation token: %w", err)
+	}
+
+	slog.Debug("Created GitHub App installation token", "installation", installationID, "expires", token.GetExpiresAt().Time)
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// installation returns the configured installation ID, looking it up once
+// when it was not set
+func (s *installationTokenSource) installation(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.installationID != 0 {
+		return s.installationID, nil
+	}
+
+	var installation *github.Installation
+	var err error
+	if s.userName != "" {
+		installation, _, err = s.client.Apps.FindUserInstallation(ctx, s.userName)
+	} else {
+		installation, _, err = s.client.Apps.FindOrganizationInstallation(ctx, s.orgName)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error finding app installation: %w", err)
+	}
+
+	s.installationID = installation.GetID()
+	slog.Info("Found GitHub App installation", "installation", s.installationID)
+	return s.installationID, nil
+}
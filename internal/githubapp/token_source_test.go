@@ -0,0 +1,201 @@
+package githubapp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+// verifyJWT checks the signature of a JWT and returns its claims
+func verifyJWT(t *testing.T, token string, key *rsa.PublicKey) map[string]interface{} {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"alg": "RS256", "typ": "JWT"}`, string(header))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func TestNewJWT(t *testing.T) {
+	key := generateKey(t)
+	now := time.Unix(1700000000, 0)
+
+	token, err := NewJWT(12345, key, now)
+	require.NoError(t, err)
+
+	claims := verifyJWT(t, token, &key.PublicKey)
+	assert.Equal(t, "12345", claims["iss"])
+	assert.Equal(t, float64(now.Add(-time.Minute).Unix()), claims["iat"])
+	assert.Equal(t, float64(now.Add(9*time.Minute).Unix()), claims["exp"])
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	key := generateKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		content     []byte
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:    "pkcs1",
+			content: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+		{
+			name:    "pkcs8",
+			content: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			name:        "not pem",
+			content:     []byte("not a key"),
+			wantErr:     true,
+			errContains: "no PEM data found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.pem")
+			require.NoError(t, os.WriteFile(path, tt.content, 0600))
+
+			loaded, err := LoadPrivateKey(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, key.Equal(loaded))
+		})
+	}
+}
+
+// fakeGitHubApp serves the installation lookup and token exchange endpoints.
+// Tokens are numbered and expire after the given lifetime.
+type fakeGitHubApp struct {
+	t        *testing.T
+	key      *rsa.PublicKey
+	lifetime time.Duration
+
+	mu      sync.Mutex
+	lookups int
+	tokens  int
+}
+
+func (f *fakeGitHubApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	claims := verifyJWT(f.t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), f.key)
+	assert.Equal(f.t, "7", claims["iss"])
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/orgs/testorg/installation":
+		f.lookups++
+		fmt.Fprint(w, `{"id": 42}`)
+	case r.Method == http.MethodPost && r.URL.Path == "/app/installations/42/access_tokens":
+		f.tokens++
+		fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, f.tokens, time.Now().Add(f.lifetime).Format(time.RFC3339))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newAppClient(t *testing.T, key *rsa.PrivateKey, handler http.Handler) *github.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(&http.Client{Transport: NewTransport(7, key, nil)})
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	return client
+}
+
+func TestTokenSource(t *testing.T) {
+	key := generateKey(t)
+	app := &fakeGitHubApp{t: t, key: &key.PublicKey, lifetime: time.Hour}
+	ts := NewTokenSource(newAppClient(t, key, app), 0, "testorg", "")
+
+	token, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_1", token.AccessToken)
+
+	// A valid token is reused
+	token, err = ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_1", token.AccessToken)
+	assert.Equal(t, 1, app.lookups)
+	assert.Equal(t, 1, app.tokens)
+}
+
+func TestTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	key := generateKey(t)
+	// Tokens expire within the refresh margin, so every call exchanges a new one
+	app := &fakeGitHubApp{t: t, key: &key.PublicKey, lifetime: RefreshMargin - time.Minute}
+	ts := NewTokenSource(newAppClient(t, key, app), 42, "", "")
+
+	first, err := ts.Token()
+	require.NoError(t, err)
+	second, err := ts.Token()
+	require.NoError(t, err)
+
+	assert.Equal(t, "ghs_1", first.AccessToken)
+	assert.Equal(t, "ghs_2", second.AccessToken)
+	assert.Zero(t, app.lookups)
+}
+
+func TestTokenSource_InstallationNotFound(t *testing.T) {
+	key := generateKey(t)
+	app := &fakeGitHubApp{t: t, key: &key.PublicKey, lifetime: time.Hour}
+	ts := NewTokenSource(newAppClient(t, key, app), 0, "", "someone")
+
+	_, err := ts.Token()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error finding app installation")
+}
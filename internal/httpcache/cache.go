@@ -0,0 +1,204 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// entry is a cached response, stored as a JSON file
+type entry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// Cache is a persistent cache of API responses, revalidated with conditional
+// requests. Only successful GET responses that carry an ETag or Last-Modified
+// header are cached.
+type Cache struct {
+	dir      string
+	identity string
+	hits     atomic.Int64
+	requests atomic.Int64
+}
+
+// New creates a cache that stores responses in dir. Entries are keyed by the
+// request credentials, unless identity is set. A stable identity lets
+// short-lived credentials, such as app installation tokens, share entries.
+func New(dir, identity string) *Cache {
+	return &Cache{
+		dir:      dir,
+		identity: identity,
+	}
+}
+
+// Stats returns the number of responses served from the cache, and the
+// number of cacheable requests made
+func (c *Cache) Stats() (hits, requests int) {
+	return int(c.hits.Load()), int(c.requests.Load())
+}
+
+// Transport returns an http.RoundTripper that serves requests from the cache
+// when the server answers 304 Not Modified
+func (c *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{cache: c, base: base}
+}
+
+// transport revalidates cached responses through the base transport
+type transport struct {
+	cache *Cache
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+	t.cache.requests.Add(1)
+
+	key := t.cache.key(req)
+	cached := t.cache.load(key)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.cache.hits.Add(1)
+		return cached.response(req, resp), nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.cache.store(key, &entry{
+		URL:          req.URL.String(),
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+	})
+	return resp, nil
+}
+
+// response rebuilds the cached response. Headers of the 304 response, such as
+// the rate limit budget, take precedence over the cached ones.
+func (e *entry) response(req *http.Request, notModified *http.Response) *http.Response {
+	header := e.Header.Clone()
+	for name, values := range notModified.Header {
+		if name != "Content-Length" {
+			header[name] = values
+		}
+	}
+	header.Set("X-From-Cache", "1")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// key identifies a request. The credentials are part of the key, so responses
+// are never shared between tokens with different access.
+func (c *Cache) key(req *http.Request) string {
+	identity := c.identity
+	if identity == "" {
+		identity = req.Header.Get("Authorization")
+	}
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + identity + "\n" + req.Header.Get("Accept")))
+	return hex.EncodeToString(sum[:])
+}
+
+// load returns the cached entry for key, or nil when there is none
+func (c *Cache) load(key string) *entry {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		slog.Debug("Ignoring unreadable HTTP cache entry", "key", key, "error", err)
+		return nil
+	}
+	return &e
+}
+
+// store writes an entry atomically, so concurrent runs never read a partial file
+func (c *Cache) store(key string, e *entry) {
+	if err := c.write(key, e); err != nil {
+		slog.Warn("Failed to write HTTP cache entry", "url", e.URL, "error", err)
+	}
+}
+
+func (c *Cache) write(key string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry: %w", err)
+	}
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
@@ -0,0 +1,133 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conditionalServer serves a fixed body with an ETag and answers matching
+// conditional requests with 304 Not Modified
+func conditionalServer(t *testing.T, etag string) (*httptest.Server, *int) {
+	t.Helper()
+
+	fullResponses := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Link", `<https://api.github.com/orgs/testorg/repos?page=2>; rel="next"`)
+		w.Write([]byte(`[{"name": "repo1"}]`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &fullResponses
+}
+
+func get(t *testing.T, client *http.Client, url, token string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestTransport_ServesNotModifiedFromCache(t *testing.T) {
+	server, fullResponses := conditionalServer(t, `"v1"`)
+	dir := t.TempDir()
+
+	cache := New(dir, "")
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	resp, body := get(t, client, server.URL, "token")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `[{"name": "repo1"}]`, body)
+	assert.Empty(t, resp.Header.Get("X-From-Cache"))
+
+	// A later run revalidates the entry written by the first
+	cache = New(dir, "")
+	client = &http.Client{Transport: cache.Transport(nil)}
+
+	resp, body = get(t, client, server.URL, "token")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `[{"name": "repo1"}]`, body)
+	assert.Equal(t, "1", resp.Header.Get("X-From-Cache"))
+	assert.Contains(t, resp.Header.Get("Link"), `rel="next"`)
+	assert.Equal(t, 1, *fullResponses)
+
+	hits, requests := cache.Stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 1, requests)
+}
+
+func TestTransport_SeparatesCredentials(t *testing.T) {
+	server, fullResponses := conditionalServer(t, `"v1"`)
+	cache := New(t.TempDir(), "")
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	get(t, client, server.URL, "token-a")
+	get(t, client, server.URL, "token-b")
+	get(t, client, server.URL, "token-a")
+
+	assert.Equal(t, 2, *fullResponses)
+	hits, requests := cache.Stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 3, requests)
+}
+
+func TestTransport_SkipsUncacheableResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		if r.URL.Path == "/missing" {
+			w.Header().Set("ETag", `"gone"`)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := New(dir, "")
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	for i := 0; i < 2; i++ {
+		get(t, client, server.URL+"/no-validators", "token")
+		get(t, client, server.URL+"/missing", "token")
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	hits, _ := cache.Stats()
+	assert.Zero(t, hits)
+}
+
+func TestTransport_SharesEntriesByIdentity(t *testing.T) {
+	server, fullResponses := conditionalServer(t, `"v1"`)
+	cache := New(t.TempDir(), "app:1:installation:42")
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	get(t, client, server.URL, "installation-token-1")
+	resp, _ := get(t, client, server.URL, "installation-token-2")
+
+	assert.Equal(t, "1", resp.Header.Get("X-From-Cache"))
+	assert.Equal(t, 1, *fullResponses)
+}
@@ -0,0 +1,231 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secondaryLimitWait is how long to wait after a secondary rate limit
+// response that carries no Retry-After header, as recommended by GitHub
+const secondaryLimitWait = time.Minute
+
+// resetMargin is added to the primary rate limit reset time, which only has
+// a resolution of one second
+const resetMargin = time.Second
+
+// maxErrorBody caps how much of a 403 response body is read to tell a
+// secondary rate limit from a permission error
+const maxErrorBody = 64 << 10
+
+// Transport is an http.RoundTripper that waits out GitHub rate limits instead
+// of failing. When the primary rate limit is exhausted, every request made
+// through the transport waits until the limit resets. Requests rejected by a
+// primary or secondary rate limit are retried after the advertised delay.
+type Transport struct {
+	base       http.RoundTripper
+	maxRetries int
+
+	mu           sync.Mutex
+	blockedUntil time.Time
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewTransport creates a rate limit aware transport around base. A rate
+// limited request is retried at most maxRetries times.
+func NewTransport(base http.RoundTripper, maxRetries int) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:       base,
+		maxRetries: maxRetries,
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitUntilUnblocked(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		wait, limited := t.retryDelay(resp)
+		if !limited {
+			// Wait out an exhausted budget before returning, so the caller
+			// does not fail the next request without sending it
+			if reset, ok := t.exhaustedUntil(resp); ok {
+				t.block(reset, "primary")
+				if err := t.waitUntilUnblocked(ctx); err != nil {
+					resp.Body.Close()
+					return nil, err
+				}
+			}
+			return resp, nil
+		}
+
+		if attempt >= t.maxRetries || !rewindable(req) {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		kind := "secondary"
+		if resp.Header.Get("Retry-After") == "" && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			kind = "primary"
+		}
+		t.block(t.now().Add(wait), kind)
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay reports whether a response was rejected by a rate limit, and how
+// long to wait before retrying it
+func (t *Transport) retryDelay(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return date.Sub(t.now()), true
+		}
+	}
+
+	if reset, ok := t.exhaustedUntil(resp); ok {
+		return reset.Sub(t.now()), true
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || isSecondaryLimit(resp) {
+		return secondaryLimitWait, true
+	}
+	// Any other 403 is a permission error
+	return 0, false
+}
+
+// isSecondaryLimit reports whether the body of a 403 response describes a
+// secondary rate limit. The body is restored so the caller can still read it.
+func isSecondaryLimit(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden || resp.Body == nil {
+		return false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+
+	var errResp struct {
+		Message          string `json:"message"`
+		DocumentationURL string `json:"documentation_url"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(errResp.Message), "secondary rate limit") ||
+		strings.Contains(errResp.DocumentationURL, "secondary-rate-limits")
+}
+
+// exhaustedUntil returns the reset time of the primary rate limit when the
+// response reports no remaining requests
+func (t *Transport) exhaustedUntil(resp *http.Response) (time.Time, bool) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}, false
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0).Add(resetMargin), true
+}
+
+// block holds back every request of the transport until the given time
+func (t *Transport) block(until time.Time, kind string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !until.After(t.blockedUntil) {
+		return
+	}
+	t.blockedUntil = until
+	slog.Warn("GitHub API rate limit reached, waiting",
+		"limit", kind,
+		"until", until.Format(time.RFC3339),
+		"wait", until.Sub(t.now()).Round(time.Second))
+}
+
+// waitUntilUnblocked sleeps until the transport is no longer rate limited
+func (t *Transport) waitUntilUnblocked(ctx context.Context) error {
+	t.mu.Lock()
+	wait := t.blockedUntil.Sub(t.now())
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	return t.sleep(ctx, wait)
+}
+
+// rewindable reports whether the request body can be sent again
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of the request with a fresh body
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// sleepContext sleeps for d, returning early when ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,226 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock records sleeps and advances time instead of sleeping
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func newTestTransport(maxRetries int) (*Transport, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	transport := NewTransport(http.DefaultTransport, maxRetries)
+	transport.now = clock.Now
+	transport.sleep = clock.Sleep
+	return transport, clock
+}
+
+// limitedServer answers the first limited requests with the given status,
+// headers and body, and every later request with 200 OK and a full budget.
+// An empty body defaults to the primary rate limit message.
+func limitedServer(t *testing.T, limited int, status int, headers map[string]string, body string) (*httptest.Server, *int) {
+	t.Helper()
+
+	if body == "" {
+		body = `{"message": "API rate limit exceeded"}`
+	}
+
+	requests := 0
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		if n <= limited {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestTransport(t *testing.T) {
+	reset := strconv.FormatInt(time.Unix(1700000000, 0).Add(30*time.Second).Unix(), 10)
+
+	tests := []struct {
+		name         string
+		limited      int
+		status       int
+		headers      map[string]string
+		body         string
+		maxRetries   int
+		wantStatus   int
+		wantRequests int
+		wantSleeps   []time.Duration
+	}{
+		{
+			name:         "primary limit waits until reset",
+			limited:      1,
+			status:       http.StatusForbidden,
+			headers:      map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantSleeps:   []time.Duration{31 * time.Second},
+		},
+		{
+			name:         "secondary limit honours retry after",
+			limited:      2,
+			status:       http.StatusForbidden,
+			headers:      map[string]string{"Retry-After": "5"},
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+			wantSleeps:   []time.Duration{5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:         "secondary limit without retry after waits a minute",
+			limited:      1,
+			status:       http.StatusForbidden,
+			headers:      map[string]string{"X-RateLimit-Remaining": "4000"},
+			body:         `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`,
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantSleeps:   []time.Duration{time.Minute},
+		},
+		{
+			name:         "secondary limit detected by documentation url",
+			limited:      1,
+			status:       http.StatusForbidden,
+			headers:      map[string]string{"X-RateLimit-Remaining": "4000"},
+			body:         `{"message": "Forbidden", "documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`,
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantSleeps:   []time.Duration{time.Minute},
+		},
+		{
+			name:         "too many requests without headers",
+			limited:      1,
+			status:       http.StatusTooManyRequests,
+			maxRetries:   3,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantSleeps:   []time.Duration{time.Minute},
+		},
+		{
+			name:         "permission error is not retried",
+			limited:      1,
+			status:       http.StatusForbidden,
+			maxRetries:   3,
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		{
+			name:         "retries exhausted",
+			limited:      5,
+			status:       http.StatusTooManyRequests,
+			headers:      map[string]string{"Retry-After": "1"},
+			maxRetries:   2,
+			wantStatus:   http.StatusTooManyRequests,
+			wantRequests: 3,
+			wantSleeps:   []time.Duration{time.Second, time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := limitedServer(t, tt.limited, tt.status, tt.headers, tt.body)
+			transport, clock := newTestTransport(tt.maxRetries)
+			client := &http.Client{Transport: transport}
+
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantRequests, *requests)
+			assert.Equal(t, tt.wantSleeps, clock.sleeps)
+		})
+	}
+}
+
+func TestTransport_ExhaustedBudget(t *testing.T) {
+	// The GitHub client checks the reset time against the real clock, so
+	// this test waits for a real reset one second ahead
+	reset := time.Now().Add(time.Second)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Write([]byte(`[{"name": "repo1"}]`))
+	}))
+	defer server.Close()
+
+	client := github.NewClient(&http.Client{Transport: NewTransport(http.DefaultTransport, 3)})
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	// The second call is not rejected by the client's own rate limit check,
+	// because the transport waited for the reset before returning the first
+	for i := 0; i < 2; i++ {
+		repos, _, err := client.Repositories.ListByOrg(context.Background(), "testorg", nil)
+		require.NoError(t, err)
+		assert.Len(t, repos, 1)
+	}
+	assert.Equal(t, 2, requests)
+	assert.True(t, time.Now().After(reset))
+}
+
+func TestTransport_ContextCanceled(t *testing.T) {
+	server, requests := limitedServer(t, 1, http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}, "")
+	transport, _ := newTestTransport(3)
+	transport.sleep = sleepContext
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Do(req)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, *requests)
+}
@@ -6,8 +6,8 @@ import (
 	"log/slog"
 	"sync"
 
-	"github.com/google/go-github/v60/github"
 	"github.com/truemilk/ghloner/internal/repository/progress"
+	"github.com/truemilk/ghloner/internal/repository/provider"
 )
 
 // WorkerPool manages concurrent execution of tasks
@@ -31,8 +31,8 @@ func (p *WorkerPool) SetProgressTracker(tracker *progress.ProgressTracker) {
 // ProcessRepositories processes a slice of repositories concurrently
 func (p *WorkerPool) ProcessRepositories(
 	ctx context.Context,
-	repos []*github.Repository,
-	processFunc func(*github.Repository) error,
+	repos []*provider.Repository,
+	processFunc func(*provider.Repository) error,
 ) error {
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, p.workers)
@@ -45,11 +45,11 @@ func (p *WorkerPool) ProcessRepositories(
 		default:
 			wg.Add(1)
 			semaphore <- struct{}{}
-			go func(repo *github.Repository, index int, workerID int) {
+			go func(repo *provider.Repository, index int, workerID int) {
 				defer wg.Done()
 				defer func() { <-semaphore }()
 				
-				repoName := *repo.Name
+				repoName := repo.FullName()
 				
 				// Report start to progress tracker
 				if p.progressTracker != nil {
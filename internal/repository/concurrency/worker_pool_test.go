@@ -7,9 +7,9 @@ import (
 	"testing"
 	"time"
 
-	"github.com/google/go-github/v60/github"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/repository/provider"
 )
 
 func TestNewWorkerPool(t *testing.T) {
@@ -42,11 +42,11 @@ func TestNewWorkerPool(t *testing.T) {
 
 func TestWorkerPool_ProcessRepositories(t *testing.T) {
 	tests := []struct {
-		name         string
-		workers      int
-		repoCount    int
-		shouldError  []int // indices of repos that should error
-		expectError  bool
+		name        string
+		workers     int
+		repoCount   int
+		shouldError []int // indices of repos that should error
+		expectError bool
 	}{
 		{
 			name:      "process all repositories successfully",
@@ -72,12 +72,10 @@ func TestWorkerPool_ProcessRepositories(t *testing.T) {
 			pool := NewWorkerPool(tt.workers)
 
 			// Create test repositories
-			repos := make([]*github.Repository, tt.repoCount)
+			repos := make([]*provider.Repository, tt.repoCount)
 			for i := 0; i < tt.repoCount; i++ {
 				name := "repo-" + string(rune('a'+i))
-				repos[i] = &github.Repository{
-					Name: github.String(name),
-				}
+				repos[i] = &provider.Repository{Name: name}
 			}
 
 			// Track which repos were processed and errors
@@ -88,9 +86,9 @@ func TestWorkerPool_ProcessRepositories(t *testing.T) {
 			}
 
 			// Process repositories
-			err := pool.ProcessRepositories(ctx, repos, func(repo *github.Repository) error {
+			err := pool.ProcessRepositories(ctx, repos, func(repo *provider.Repository) error {
 				atomic.AddInt32(&processed, 1)
-				
+
 				// Find index of this repo
 				for i, r := range repos {
 					if r == repo {
@@ -100,7 +98,7 @@ func TestWorkerPool_ProcessRepositories(t *testing.T) {
 						break
 					}
 				}
-				
+
 				time.Sleep(10 * time.Millisecond)
 				return nil
 			})
@@ -117,11 +115,9 @@ func TestWorkerPool_ProcessWithContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create test repositories
-	repos := make([]*github.Repository, 10)
+	repos := make([]*provider.Repository, 10)
 	for i := 0; i < 10; i++ {
-		repos[i] = &github.Repository{
-			Name: github.String("repo-" + string(rune('a'+i))),
-		}
+		repos[i] = &provider.Repository{Name: "repo-" + string(rune('a'+i))}
 	}
 
 	var processedCount int32
@@ -133,7 +129,7 @@ func TestWorkerPool_ProcessWithContextCancellation(t *testing.T) {
 	}()
 
 	// Process repositories
-	err := pool.ProcessRepositories(ctx, repos, func(repo *github.Repository) error {
+	err := pool.ProcessRepositories(ctx, repos, func(repo *provider.Repository) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -146,7 +142,7 @@ func TestWorkerPool_ProcessWithContextCancellation(t *testing.T) {
 	// Should have context cancellation error
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "interrupted")
-	
+
 	// Some repos might have been processed before cancellation
 	processed := atomic.LoadInt32(&processedCount)
 	assert.True(t, processed < 10, "Not all repositories should be processed")
@@ -157,7 +153,7 @@ func TestWorkerPool_ProcessEmptyRepositories(t *testing.T) {
 	ctx := context.Background()
 
 	// Process empty repository list
-	err := pool.ProcessRepositories(ctx, []*github.Repository{}, func(repo *github.Repository) error {
+	err := pool.ProcessRepositories(ctx, []*provider.Repository{}, func(repo *provider.Repository) error {
 		return nil
 	})
 
@@ -172,20 +168,18 @@ func TestWorkerPool_ConcurrentProcessing(t *testing.T) {
 	// Track concurrent executions
 	var concurrentCount int32
 	maxConcurrent := int32(0)
-	
+
 	// Create test repositories
-	repos := make([]*github.Repository, 20)
+	repos := make([]*provider.Repository, 20)
 	for i := 0; i < 20; i++ {
-		repos[i] = &github.Repository{
-			Name: github.String("repo-" + string(rune('a'+i))),
-		}
+		repos[i] = &provider.Repository{Name: "repo-" + string(rune('a'+i))}
 	}
 
 	// Process repositories
-	err := pool.ProcessRepositories(ctx, repos, func(repo *github.Repository) error {
+	err := pool.ProcessRepositories(ctx, repos, func(repo *provider.Repository) error {
 		// Increment concurrent count
 		current := atomic.AddInt32(&concurrentCount, 1)
-		
+
 		// Update max if needed
 		for {
 			max := atomic.LoadInt32(&maxConcurrent)
@@ -193,10 +187,10 @@ func TestWorkerPool_ConcurrentProcessing(t *testing.T) {
 				break
 			}
 		}
-		
+
 		// Simulate work
 		time.Sleep(20 * time.Millisecond)
-		
+
 		// Decrement concurrent count
 		atomic.AddInt32(&concurrentCount, -1)
 		return nil
@@ -204,7 +198,7 @@ func TestWorkerPool_ConcurrentProcessing(t *testing.T) {
 
 	// Verify no errors
 	assert.NoError(t, err)
-	
+
 	// Verify concurrent processing occurred
 	assert.True(t, maxConcurrent > 1, "Should have processed repositories concurrently")
 	assert.True(t, maxConcurrent <= 5, "Should not exceed worker pool size")
@@ -219,18 +213,16 @@ func TestWorkerPool_LongRunningTasks(t *testing.T) {
 	ctx := context.Background()
 
 	start := time.Now()
-	
+
 	// Create 4 repositories
-	repos := make([]*github.Repository, 4)
+	repos := make([]*provider.Repository, 4)
 	for i := 0; i < 4; i++ {
-		repos[i] = &github.Repository{
-			Name: github.String("repo-" + string(rune('a'+i))),
-		}
+		repos[i] = &provider.Repository{Name: "repo-" + string(rune('a'+i))}
 	}
 
 	// Process repositories with 100ms delay each
 	// With 2 workers, this should take ~200ms total
-	err := pool.ProcessRepositories(ctx, repos, func(repo *github.Repository) error {
+	err := pool.ProcessRepositories(ctx, repos, func(repo *provider.Repository) error {
 		time.Sleep(100 * time.Millisecond)
 		return nil
 	})
@@ -239,8 +231,8 @@ func TestWorkerPool_LongRunningTasks(t *testing.T) {
 
 	// Verify no errors
 	assert.NoError(t, err)
-	
+
 	// Verify parallel processing (should take ~200ms, not 400ms)
 	assert.True(t, elapsed < 300*time.Millisecond, "Repositories should be processed in parallel")
 	assert.True(t, elapsed > 150*time.Millisecond, "Processing should take expected time")
-}
\ No newline at end of file
+}
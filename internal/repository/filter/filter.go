@@ -0,0 +1,211 @@
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// propertyExpression matches a custom property value, or any value when value is empty
+type propertyExpression struct {
+	name  string
+	value string
+}
+
+// Filter selects repositories based on their metadata
+type Filter struct {
+	config            *config.Config
+	includeRegex      *regexp.Regexp
+	excludeRegex      *regexp.Regexp
+	includeProperties map[string][]propertyExpression
+	excludeProperties []propertyExpression
+	now               func() time.Time
+}
+
+// New creates a new repository filter from the configuration
+func New(cfg *config.Config) (*Filter, error) {
+	f := &Filter{
+		config: cfg,
+		now:    time.Now,
+	}
+
+	var err error
+	if cfg.IncludeRegex != "" {
+		if f.includeRegex, err = regexp.Compile(cfg.IncludeRegex); err != nil {
+			return nil, fmt.Errorf("invalid include regex: %w", err)
+		}
+	}
+	if cfg.ExcludeRegex != "" {
+		if f.excludeRegex, err = regexp.Compile(cfg.ExcludeRegex); err != nil {
+			return nil, fmt.Errorf("invalid exclude regex: %w", err)
+		}
+	}
+	for _, pattern := range append(append([]string{}, cfg.IncludeNames...), cfg.ExcludeNames...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Include expressions for the same property are alternatives, different properties must all match
+	f.includeProperties = make(map[string][]propertyExpression)
+	for _, expr := range cfg.Properties {
+		p, err := parsePropertyExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		f.includeProperties[p.name] = append(f.includeProperties[p.name], p)
+	}
+	for _, expr := range cfg.ExcludeProperties {
+		p, err := parsePropertyExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		f.excludeProperties = append(f.excludeProperties, p)
+	}
+
+	return f, nil
+}
+
+// NeedsProperties reports whether the filter uses custom property values
+func (f *Filter) NeedsProperties() bool {
+	return len(f.includeProperties) > 0 || len(f.excludeProperties) > 0
+}
+
+// parsePropertyExpression parses a name=value or name property expression
+func parsePropertyExpression(expr string) (propertyExpression, error) {
+	name, value, _ := strings.Cut(expr, "=")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return propertyExpression{}, fmt.Errorf("invalid property expression %q: missing property name", expr)
+	}
+	return propertyExpression{name: name, value: strings.TrimSpace(value)}, nil
+}
+
+// Apply splits repositories into those selected by the filter and those filtered out
+func (f *Filter) Apply(repos []*provider.Repository) (selected, filtered []*provider.Repository) {
+	for _, repo := range repos {
+		if f.Match(repo) {
+			selected = append(selected, repo)
+		} else {
+			filtered = append(filtered, repo)
+		}
+	}
+	return selected, filtered
+}
+
+// Match reports whether a repository passes every configured filter
+func (f *Filter) Match(repo *provider.Repository) bool {
+	cfg := f.config
+
+	if len(cfg.IncludeNames) > 0 && !matchAnyName(cfg.IncludeNames, repo) {
+		return false
+	}
+	if matchAnyName(cfg.ExcludeNames, repo) {
+		return false
+	}
+	if f.includeRegex != nil && !f.includeRegex.MatchString(repo.FullName()) {
+		return false
+	}
+	if f.excludeRegex != nil && f.excludeRegex.MatchString(repo.FullName()) {
+		return false
+	}
+
+	if len(cfg.Topics) > 0 && !containsAny(cfg.Topics, repo.Topics) {
+		return false
+	}
+	if containsAny(cfg.ExcludeTopics, repo.Topics) {
+		return false
+	}
+	if len(cfg.Languages) > 0 && !containsAny(cfg.Languages, []string{repo.Language}) {
+		return false
+	}
+	if containsAny(cfg.ExcludeLanguages, []string{repo.Language}) {
+		return false
+	}
+
+	if !matchMode(cfg.Archived, repo.Archived) || !matchMode(cfg.Forks, repo.Fork) {
+		return false
+	}
+	if len(cfg.Visibility) > 0 && !containsAny(cfg.Visibility, []string{repo.Visibility}) {
+		return false
+	}
+
+	if cfg.MinSize > 0 && repo.Size < cfg.MinSize {
+		return false
+	}
+	if cfg.MaxSize > 0 && repo.Size > cfg.MaxSize {
+		return false
+	}
+	if cfg.PushedWithin > 0 && (repo.PushedAt.IsZero() || f.now().Sub(repo.PushedAt) > cfg.PushedWithin) {
+		return false
+	}
+
+	for _, alternatives := range f.includeProperties {
+		if !matchAnyProperty(alternatives, repo) {
+			return false
+		}
+	}
+	if matchAnyProperty(f.excludeProperties, repo) {
+		return false
+	}
+
+	return true
+}
+
+// matchAnyProperty reports whether a repository matches one of the property expressions
+func matchAnyProperty(exprs []propertyExpression, repo *provider.Repository) bool {
+	for _, expr := range exprs {
+		values := repo.Properties[expr.name]
+		if len(values) == 0 {
+			continue
+		}
+		if expr.value == "" || containsAny([]string{expr.value}, values) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyName reports whether a repository matches one of the glob patterns.
+// Patterns containing a slash are matched against the full name.
+func matchAnyName(patterns []string, repo *provider.Repository) bool {
+	for _, pattern := range patterns {
+		name := repo.Name
+		if strings.Contains(pattern, "/") {
+			name = repo.FullName()
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether any value is in the list, ignoring case
+func containsAny(list, values []string) bool {
+	for _, item := range list {
+		for _, value := range values {
+			if strings.EqualFold(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchMode applies an include, exclude or only filter mode to a flag
+func matchMode(mode string, value bool) bool {
+	switch mode {
+	case config.FilterExclude:
+		return !value
+	case config.FilterOnly:
+		return value
+	default:
+		return true
+	}
+}
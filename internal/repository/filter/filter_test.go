@@ -0,0 +1,178 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+func TestNew_InvalidPatterns(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		errContains string
+	}{
+		{
+			name:        "invalid include regex",
+			cfg:         &config.Config{IncludeRegex: "("},
+			errContains: "invalid include regex",
+		},
+		{
+			name:        "invalid exclude regex",
+			cfg:         &config.Config{ExcludeRegex: "[a-"},
+			errContains: "invalid exclude regex",
+		},
+		{
+			name:        "property expression without name",
+			cfg:         &config.Config{Properties: []string{"=critical"}},
+			errContains: "missing property name",
+		},
+		{
+			name:        "invalid glob",
+			cfg:         &config.Config{ExcludeNames: []string{"[a-"}},
+			errContains: "invalid name pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	repo := &provider.Repository{
+		Owner:      "testorg",
+		Name:       "api-gateway",
+		Visibility: "internal",
+		Language:   "Go",
+		Topics:     []string{"backend", "critical"},
+		Size:       2048,
+		PushedAt:   now.Add(-48 * time.Hour),
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		expected bool
+	}{
+		{name: "no filters", cfg: config.Config{}, expected: true},
+		{name: "include glob matches", cfg: config.Config{IncludeNames: []string{"web-*", "api-*"}}, expected: true},
+		{name: "include glob misses", cfg: config.Config{IncludeNames: []string{"web-*"}}, expected: false},
+		{name: "include glob on full name", cfg: config.Config{IncludeNames: []string{"testorg/api-*"}}, expected: true},
+		{name: "exclude glob", cfg: config.Config{ExcludeNames: []string{"*-gateway"}}, expected: false},
+		{name: "include regex", cfg: config.Config{IncludeRegex: "^testorg/api-"}, expected: true},
+		{name: "exclude regex", cfg: config.Config{ExcludeRegex: "gateway$"}, expected: false},
+		{name: "topic matches", cfg: config.Config{Topics: []string{"critical"}}, expected: true},
+		{name: "topic misses", cfg: config.Config{Topics: []string{"frontend"}}, expected: false},
+		{name: "exclude topic", cfg: config.Config{ExcludeTopics: []string{"backend"}}, expected: false},
+		{name: "language matches ignoring case", cfg: config.Config{Languages: []string{"go"}}, expected: true},
+		{name: "language misses", cfg: config.Config{Languages: []string{"Rust"}}, expected: false},
+		{name: "exclude language", cfg: config.Config{ExcludeLanguages: []string{"Go"}}, expected: false},
+		{name: "only archived", cfg: config.Config{Archived: config.FilterOnly}, expected: false},
+		{name: "exclude archived", cfg: config.Config{Archived: config.FilterExclude}, expected: true},
+		{name: "only forks", cfg: config.Config{Forks: config.FilterOnly}, expected: false},
+		{name: "visibility matches", cfg: config.Config{Visibility: []string{"internal", "private"}}, expected: true},
+		{name: "visibility misses", cfg: config.Config{Visibility: []string{"public"}}, expected: false},
+		{name: "below minimum size", cfg: config.Config{MinSize: 4096}, expected: false},
+		{name: "above maximum size", cfg: config.Config{MaxSize: 1024}, expected: false},
+		{name: "within size range", cfg: config.Config{MinSize: 1024, MaxSize: 4096}, expected: true},
+		{name: "pushed recently", cfg: config.Config{PushedWithin: 72 * time.Hour}, expected: true},
+		{name: "pushed too long ago", cfg: config.Config{PushedWithin: 24 * time.Hour}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(&tt.cfg)
+			require.NoError(t, err)
+			f.now = func() time.Time { return now }
+
+			assert.Equal(t, tt.expected, f.Match(repo))
+		})
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	repos := []*provider.Repository{
+		{Owner: "testorg", Name: "service", Archived: false},
+		{Owner: "testorg", Name: "old-service", Archived: true},
+		{Owner: "testorg", Name: "fork", Fork: true},
+	}
+
+	f, err := New(&config.Config{Archived: config.FilterExclude, Forks: config.FilterExclude})
+	require.NoError(t, err)
+
+	selected, filtered := f.Apply(repos)
+	require.Len(t, selected, 1)
+	assert.Equal(t, "service", selected[0].Name)
+	assert.Len(t, filtered, 2)
+}
+
+func TestFilter_Properties(t *testing.T) {
+	critical := &provider.Repository{Name: "payments", Properties: map[string][]string{
+		"tier": {"critical"},
+		"team": {"billing", "platform"},
+	}}
+	restricted := &provider.Repository{Name: "ledger", Properties: map[string][]string{
+		"tier":                {"critical"},
+		"data-classification": {"restricted"},
+	}}
+	low := &provider.Repository{Name: "docs", Properties: map[string][]string{"tier": {"low"}}}
+	unset := &provider.Repository{Name: "scratch"}
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		expected []string
+	}{
+		{
+			name:     "include by value",
+			cfg:      config.Config{Properties: []string{"tier=critical"}},
+			expected: []string{"payments", "ledger"},
+		},
+		{
+			name:     "values of one property are alternatives",
+			cfg:      config.Config{Properties: []string{"tier=critical", "tier=low"}},
+			expected: []string{"payments", "ledger", "docs"},
+		},
+		{
+			name:     "different properties must all match",
+			cfg:      config.Config{Properties: []string{"tier=critical", "team=platform"}},
+			expected: []string{"payments"},
+		},
+		{
+			name:     "property set to any value",
+			cfg:      config.Config{Properties: []string{"tier"}},
+			expected: []string{"payments", "ledger", "docs"},
+		},
+		{
+			name:     "exclude by value",
+			cfg:      config.Config{ExcludeProperties: []string{"data-classification=restricted"}},
+			expected: []string{"payments", "docs", "scratch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(&tt.cfg)
+			require.NoError(t, err)
+			assert.True(t, f.NeedsProperties())
+
+			selected, _ := f.Apply([]*provider.Repository{critical, restricted, low, unset})
+			var names []string
+			for _, repo := range selected {
+				names = append(names, repo.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/storage"
+)
+
+const (
+	// backupRefPrefix is where the previous tips of rewritten branches are kept
+	backupRefPrefix = "refs/ghloner/overwritten/"
+	// stateRefPrefix holds every ref ghloner creates itself
+	stateRefPrefix = "refs/ghloner/"
+	// timestampFormat is the UTC time in the names of the refs and directories
+	// ghloner creates
+	timestampFormat = "20060102T150405Z"
+)
+
+// Backup is the previous tip of a branch whose history was rewritten or
+// which was deleted on the remote
+type Backup struct {
+	// Repository is the path of the clone in the output directory
+	Repository string
+	Branch     string
+	Time       time.Time
+	Ref        string
+	Hash       plumbing.Hash
+}
+
+// GetBackups returns the backups of a clone, oldest first
+func (o *Operations) GetBackups(repo *git.Repository) ([]Backup, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("error listing references: %w", err)
+	}
+
+	var backups []Backup
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if !strings.HasPrefix(name, backupRefPrefix) {
+			return nil
+		}
+		branch, stamp := path.Split(strings.TrimPrefix(name, backupRefPrefix))
+		t, err := time.Parse(timestampFormat, stamp)
+		if err != nil || branch == "" {
+			// Not created by ghloner
+			return nil
+		}
+		backups = append(backups, Backup{
+			Branch: strings.TrimSuffix(branch, "/"),
+			Time:   t,
+			Ref:    name,
+			Hash:   ref.Hash(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing references: %w", err)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.Before(backups[j].Time)
+		}
+		return backups[i].Branch < backups[j].Branch
+	})
+	return backups, nil
+}
+
+// PruneBackups deletes the backups made before cutoff. It returns the number
+// of backups deleted.
+func (o *Operations) PruneBackups(repo *git.Repository, cutoff time.Time) (int, error) {
+	backups, err := o.GetBackups(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, b := range backups {
+		if !b.Time.Before(cutoff) {
+			continue
+		}
+		if err := repo.Storer.RemoveReference(plumbing.ReferenceName(b.Ref)); err != nil {
+			return pruned, fmt.Errorf("error deleting backup %s: %w", b.Ref, err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// SaveBackup keeps hash as the previous tip of branch. It returns the name of
+// the backup ref.
+func (o *Operations) SaveBackup(repo *git.Repository, branch string, hash plumbing.Hash, now time.Time) (string, error) {
+	name := plumbing.ReferenceName(backupRefPrefix + branch + "/" + now.UTC().Format(timestampFormat))
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		return "", fmt.Errorf("error saving backup of %s: %w", branch, err)
+	}
+	return name.String(), nil
+}
+
+// BackupOverwritten saves the previous tip of every branch in before, a
+// snapshot of the refs below prefix, that the remote rewrote or deleted. A
+// branch whose new history ends at the shallow boundary before its previous
+// tip may have been rewritten, so it is saved as well. It returns the names of
+// the backup refs.
+func (o *Operations) BackupOverwritten(repo *git.Repository, before map[plumbing.ReferenceName]plumbing.Hash, prefix string, now time.Time) ([]string, error) {
+	var saved []string
+	for name, old := range before {
+		ref, err := repo.Storer.Reference(name)
+		switch {
+		case errors.Is(err, plumbing.ErrReferenceNotFound):
+		case err != nil:
+			return saved, fmt.Errorf("error getting reference %s: %w", name, err)
+		case ref.Hash() == old:
+			continue
+		default:
+			kept, err := isShallowAncestor(repo, old, ref.Hash())
+			if err != nil {
+				return saved, fmt.Errorf("error comparing %s: %w", name, err)
+			}
+			if kept {
+				continue
+			}
+		}
+
+		backup, err := o.SaveBackup(repo, strings.TrimPrefix(name.String(), prefix), old, now)
+		if err != nil {
+			return saved, err
+		}
+		saved = append(saved, backup)
+	}
+	sort.Strings(saved)
+	return saved, nil
+}
+
+// snapshotRefs returns the hash of every ref below prefix
+func snapshotRefs(repo *git.Repository, prefix string) (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("error listing references: %w", err)
+	}
+
+	snapshot := make(map[plumbing.ReferenceName]plumbing.Hash)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Name().String(), prefix) {
+			snapshot[ref.Name()] = ref.Hash()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing references: %w", err)
+	}
+	return snapshot, nil
+}
+
+// restoreRefs sets the refs of a snapshot that no longer exist, such as refs
+// pruned by a mirror fetch
+func restoreRefs(repo *git.Repository, snapshot map[plumbing.ReferenceName]plumbing.Hash) error {
+	for name, hash := range snapshot {
+		_, err := repo.Storer.Reference(name)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return fmt.Errorf("error getting reference %s: %w", name, err)
+		}
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+			return fmt.Errorf("error restoring reference %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ListBackups returns the backups of every clone in the output directory
+func ListBackups(cfg *config.Config) ([]Backup, error) {
+	clones, err := storage.FindClones(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := NewOperations(1)
+	var backups []Backup
+	for _, clonePath := range clones {
+		repo, err := git.PlainOpen(clonePath)
+		if err != nil {
+			return nil, fmt.Errorf("error opening repository %s: %w", clonePath, err)
+		}
+		repoBackups, err := operations.GetBackups(repo)
+		if err != nil {
+			return nil, fmt.Errorf("error listing backups of %s: %w", clonePath, err)
+		}
+		rel, err := filepath.Rel(cfg.OutputDir, clonePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range repoBackups {
+			b.Repository = filepath.ToSlash(rel)
+			backups = append(backups, b)
+		}
+	}
+	return backups, nil
+}
@@ -0,0 +1,64 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/test/helpers"
+)
+
+func TestPruneBackups(t *testing.T) {
+	repo := helpers.CreateTestRepo(t, t.TempDir())
+	head, err := repo.Head()
+	require.NoError(t, err)
+
+	operations := NewOperations(1)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	old, err := operations.SaveBackup(repo, "feature/login", head.Hash(), now.Add(-48*time.Hour))
+	require.NoError(t, err)
+	recent, err := operations.SaveBackup(repo, "master", head.Hash(), now)
+	require.NoError(t, err)
+	assert.Equal(t, "refs/ghloner/overwritten/feature/login/20260227T120000Z", old)
+
+	backups, err := operations.GetBackups(repo)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, "feature/login", backups[0].Branch)
+	assert.Equal(t, now.Add(-48*time.Hour), backups[0].Time)
+
+	pruned, err := operations.PruneBackups(repo, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+
+	backups, err = operations.GetBackups(repo)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, recent, backups[0].Ref)
+}
+
+func TestListBackups(t *testing.T) {
+	cfg := &config.Config{OutputDir: t.TempDir()}
+	repo := helpers.CreateTestRepo(t, filepath.Join(cfg.OutputDir, "org", "repo"))
+	helpers.CreateTestRepo(t, filepath.Join(cfg.OutputDir, "org", "other"))
+	// Quarantined clones are not listed
+	quarantined := helpers.CreateTestRepo(t, cfg.StatePath("quarantine", "repo"))
+
+	operations := NewOperations(1)
+	for _, r := range []*git.Repository{repo, quarantined} {
+		head, err := r.Head()
+		require.NoError(t, err)
+		_, err = operations.SaveBackup(r, "main", head.Hash(), time.Now())
+		require.NoError(t, err)
+	}
+
+	backups, err := ListBackups(cfg)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "org/repo", backups[0].Repository)
+	assert.Equal(t, "main", backups[0].Branch)
+}
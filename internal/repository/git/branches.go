@@ -0,0 +1,169 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// remoteHEAD records the default branch of the remote, as git clone does
+var remoteHEAD = plumbing.NewRemoteHEADReferenceName("origin")
+
+// GetRemoteDefaultBranch returns the default branch recorded for the remote,
+// or an empty string when none was recorded
+func (o *Operations) GetRemoteDefaultBranch(repo *git.Repository) (string, error) {
+	ref, err := repo.Storer.Reference(remoteHEAD)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting remote HEAD: %w", err)
+	}
+	if ref.Type() != plumbing.SymbolicReference {
+		return "", nil
+	}
+	return strings.TrimPrefix(ref.Target().String(), "refs/remotes/origin/"), nil
+}
+
+// SetRemoteDefaultBranch records the default branch of the remote
+func (o *Operations) SetRemoteDefaultBranch(repo *git.Repository, branch string) error {
+	ref := plumbing.NewSymbolicReference(remoteHEAD, plumbing.NewRemoteReferenceName("origin", branch))
+	if err := repo.Storer.SetReference(ref); err != nil {
+		return fmt.Errorf("error setting remote HEAD: %w", err)
+	}
+	return nil
+}
+
+// SwitchBranch checks out branch, creating it from the remote branch when it
+// does not exist locally. It returns the commit that is checked out.
+func (o *Operations) SwitchBranch(repo *git.Repository, branch string) (plumbing.Hash, error) {
+	local := plumbing.NewBranchReferenceName(branch)
+	if _, err := repo.Reference(local, false); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		if err := createTrackingBranch(repo, branch); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	} else if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error getting branch %s: %w", branch, err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error getting worktree: %w", err)
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Branch: local}); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error checking out branch %s: %w", branch, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error getting HEAD: %w", err)
+	}
+	return head.Hash(), nil
+}
+
+// TrackRemoteBranches creates a local branch for every remote branch, and
+// fast-forwards local branches that are not checked out. Branches that
+// diverged from the remote are left alone. It returns the number of branches
+// created or updated.
+func (o *Operations) TrackRemoteBranches(repo *git.Repository, repoName string) (int, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return 0, fmt.Errorf("error getting HEAD: %w", err)
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return 0, fmt.Errorf("error listing references: %w", err)
+	}
+	var remoteBranches []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsRemote() && ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Name().String(), "refs/remotes/origin/") {
+			remoteBranches = append(remoteBranches, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error listing references: %w", err)
+	}
+
+	changed := 0
+	for _, remoteRef := range remoteBranches {
+		branch := strings.TrimPrefix(remoteRef.Name().String(), "refs/remotes/origin/")
+		local := plumbing.NewBranchReferenceName(branch)
+		if local == head.Name() {
+			continue
+		}
+
+		localRef, err := repo.Reference(local, false)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			if err := createTrackingBranch(repo, branch); err != nil {
+				return changed, err
+			}
+			changed++
+			continue
+		}
+		if err != nil {
+			return changed, fmt.Errorf("error getting branch %s: %w", branch, err)
+		}
+		if localRef.Hash() == remoteRef.Hash() {
+			continue
+		}
+
+		fastForward, err := isShallowAncestor(repo, localRef.Hash(), remoteRef.Hash())
+		if err != nil {
+			return changed, fmt.Errorf("error comparing branch %s: %w", branch, err)
+		}
+		if !fastForward {
+			slog.Warn("Did not update branch (diverged from remote)", "repository", repoName, "branch", branch)
+			continue
+		}
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(local, remoteRef.Hash())); err != nil {
+			return changed, fmt.Errorf("error updating branch %s: %w", branch, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// createTrackingBranch creates a local branch at the remote branch, with the
+// remote branch as its upstream
+func createTrackingBranch(repo *git.Repository, branch string) error {
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return fmt.Errorf("error getting remote branch %s: %w", branch, err)
+	}
+
+	local := plumbing.NewBranchReferenceName(branch)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(local, remoteRef.Hash())); err != nil {
+		return fmt.Errorf("error creating branch %s: %w", branch, err)
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("error reading repository config: %w", err)
+	}
+	cfg.Branches[branch] = &gitconfig.Branch{Name: branch, Remote: "origin", Merge: local}
+	if err := repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("error configuring branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// setSingleBranch makes the origin remote fetch only branch
+func setSingleBranch(repo *git.Repository, branch string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	remote, ok := cfg.Remotes["origin"]
+	if !ok {
+		return fmt.Errorf("remote origin not found")
+	}
+	remote.Fetch = []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch))}
+	return repo.SetConfig(cfg)
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// stashRefPrefix is where local changes are saved by the stash policy
+const stashRefPrefix = "refs/ghloner/stash/"
+
+// LocalChanges describes work in a clone that an update would overwrite
+type LocalChanges struct {
+	// Uncommitted are changes to tracked files, staged or not
+	Uncommitted bool
+	// Untracked are files that are neither tracked nor ignored
+	Untracked bool
+	// Commits are commits that were never pushed to the remote branch
+	Commits bool
+}
+
+// Any reports whether there are local changes
+func (c LocalChanges) Any() bool {
+	return c.Uncommitted || c.Untracked || c.Commits
+}
+
+// String lists the kinds of local changes, e.g. "uncommitted changes, local commits"
+func (c LocalChanges) String() string {
+	var kinds []string
+	if c.Uncommitted {
+		kinds = append(kinds, "uncommitted changes")
+	}
+	if c.Untracked {
+		kinds = append(kinds, "untracked files")
+	}
+	if c.Commits {
+		kinds = append(kinds, "local commits")
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// GetLocalChanges inspects the worktree, and the commits of HEAD that are not
+// reachable from tracked, the remote branch as last fetched. Local commits are
+// not checked when tracked is zero.
+func (o *Operations) GetLocalChanges(repo *git.Repository, tracked plumbing.Hash) (LocalChanges, error) {
+	var changes LocalChanges
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return changes, fmt.Errorf("error getting worktree: %w", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		return changes, fmt.Errorf("error getting worktree status: %w", err)
+	}
+	for _, s := range status {
+		switch {
+		case s.Worktree == git.Untracked:
+			changes.Untracked = true
+		case s.Staging != git.Unmodified || s.Worktree != git.Unmodified:
+			changes.Uncommitted = true
+		}
+	}
+
+	if tracked.IsZero() {
+		return changes, nil
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return changes, fmt.Errorf("error getting HEAD: %w", err)
+	}
+	if head.Hash() != tracked {
+		pushed, err := isShallowAncestor(repo, head.Hash(), tracked)
+		if err != nil {
+			return changes, fmt.Errorf("error comparing with remote branch: %w", err)
+		}
+		changes.Commits = !pushed
+	}
+	return changes, nil
+}
+
+// StashLocalChanges saves HEAD, with any uncommitted changes and untracked
+// files committed on top of it, to a ref below refs/ghloner/stash. The
+// worktree is then reset to target and cleaned. It returns the name of the ref.
+func (o *Operations) StashLocalChanges(repo *git.Repository, target plumbing.Hash) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("error getting HEAD: %w", err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("error getting worktree: %w", err)
+	}
+
+	saved := head.Hash()
+	status, err := w.Status()
+	if err != nil {
+		return "", fmt.Errorf("error getting worktree status: %w", err)
+	}
+	if !status.IsClean() {
+		if err := w.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+			return "", fmt.Errorf("error staging local changes: %w", err)
+		}
+		saved, err = w.Commit("Local changes saved by ghloner", &git.CommitOptions{
+			Author: &object.Signature{Name: "ghloner", Email: "ghloner@localhost", When: time.Now()},
+		})
+		if err != nil {
+			return "", fmt.Errorf("error committing local changes: %w", err)
+		}
+		// Committing moved the branch, or a detached HEAD, to the stash commit
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), head.Hash())); err != nil {
+			return "", fmt.Errorf("error restoring HEAD: %w", err)
+		}
+	}
+
+	name := plumbing.ReferenceName(stashRefPrefix + time.Now().UTC().Format(timestampFormat))
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, saved)); err != nil {
+		return "", fmt.Errorf("error saving local changes: %w", err)
+	}
+
+	if err := w.Reset(&git.ResetOptions{Commit: target, Mode: git.HardReset}); err != nil {
+		return "", fmt.Errorf("error resetting worktree: %w", err)
+	}
+	if err := w.Clean(&git.CleanOptions{Dir: true}); err != nil {
+		return "", fmt.Errorf("error removing untracked files: %w", err)
+	}
+	return name.String(), nil
+}
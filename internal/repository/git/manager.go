@@ -4,15 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/go-github/v60/github"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
 )
 
 // Manager handles Git repository operations
@@ -20,104 +25,245 @@ type Manager struct {
 	config       *config.Config
 	operations   *Operations
 	repositories map[string]*git.Repository
+	sshAuth      *SSHAuth
 	repoMutex    sync.Mutex
 	printMutex   sync.Mutex
+
+	scrubbedRemotes   atomic.Int64
+	preservedBranches atomic.Int64
+
+	reportMutex        sync.Mutex
+	branchSwitches     []string
+	localChangeReports []string
 }
 
 // NewManager creates a new Git manager
 func NewManager(cfg *config.Config) *Manager {
+	operations := NewOperations(cfg.RetryCount)
+	operations.depth = cfg.Depth
+	operations.singleBranch = cfg.SingleBranch
+	operations.allBranches = cfg.AllBranches
+
 	return &Manager{
 		config:       cfg,
-		operations:   NewOperations(cfg.RetryCount),
+		operations:   operations,
 		repositories: make(map[string]*git.Repository),
 	}
 }
 
+// SetSSHAuth sets the authentication of SSH remotes. Once set, repositories
+// are cloned and updated from their SSH URL.
+func (m *Manager) SetSSHAuth(auth *SSHAuth) {
+	m.sshAuth = auth
+}
+
 // ProcessRepository processes a single repository (clone or update)
-func (m *Manager) ProcessRepository(repo *github.Repository, orgName, token string) error {
-	repoPath := filepath.Join(m.config.OutputDir, *repo.Name)
-	cloneURL := fmt.Sprintf("https://github.com/%s/%s.git", orgName, *repo.Name)
-	authURL := fmt.Sprintf("https://%s@github.com/%s/%s.git", token, orgName, *repo.Name)
+func (m *Manager) ProcessRepository(repo *provider.Repository, token string) error {
+	repoName := filepath.ToSlash(m.config.RepositoryPath(repo.Owner, repo.Name))
+	repoPath := filepath.Join(m.config.OutputDir, m.config.RepositoryPath(repo.Owner, repo.Name))
+	// Credentials in the clone URL, such as from a manifest, authenticate each
+	// fetch instead of being stored in the remote
+	cloneURL, urlAuth := splitCredentials(m.remoteURL(repo))
+	auth := m.authMethod(cloneURL, token)
+	if auth == nil && urlAuth != nil {
+		auth = urlAuth
+	}
 
 	if _, err := os.Stat(repoPath); err == nil {
-		return m.updateRepository(repoPath, *repo.Name, authURL, token)
+		if m.config.Mirror {
+			return m.updateMirror(repoPath, repoName, cloneURL, auth)
+		}
+		return m.updateRepository(repoPath, repoName, cloneURL, repo, auth)
 	} else if os.IsNotExist(err) {
-		return m.cloneRepository(repoPath, cloneURL, *repo.Name, token)
+		if repo.Empty {
+			slog.Warn("Did not clone repository (empty)", "repository", repoName)
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
+			return fmt.Errorf("error creating owner directory: %w", err)
+		}
+		return m.cloneRepository(repoPath, cloneURL, repoName, repo.Branch, auth)
 	} else {
 		slog.Error("Failed to check directory", "path", repoPath, "error", err)
 		return err
 	}
 }
 
-// updateRepository updates an existing repository
-func (m *Manager) updateRepository(repoPath, repoName, authURL, token string) error {
+// updateRepository updates an existing repository. The fetch is skipped when
+// the checked out default branch already matches the head reported by the provider.
+// When the provider reports a new default branch and the clone is still on the
+// previous one, the new default branch is checked out. Clones with a detached or
+// unborn HEAD are put back on their branch. Local changes that the update would
+// overwrite are handled by the local changes policy first.
+func (m *Manager) updateRepository(repoPath, repoName, cloneURL string, repo *provider.Repository, auth transport.AuthMethod) error {
 	gitRepo, err := m.openRepository(repoPath, repoName)
 	if err != nil {
 		slog.Error("Failed to open repository", "repository", repoName, "error", err)
 		return err
 	}
 
-	if err := m.operations.UpdateRemoteURL(gitRepo, authURL); err != nil {
+	if err := checkMirror(gitRepo, false); err != nil {
+		slog.Error("Failed to update repository", "repository", repoName, "error", err)
+		return err
+	}
+
+	if err := m.syncRemoteURL(gitRepo, repoName, cloneURL); err != nil {
 		slog.Error("Failed to update remote URL", "repository", repoName, "error", err)
 		return err
 	}
+	m.pruneBackups(gitRepo, repoName)
 
 	branchName, beforeHash, err := m.operations.GetRepositoryHead(gitRepo)
-	if err != nil {
+	unborn := errors.Is(err, plumbing.ErrReferenceNotFound)
+	if err != nil && !unborn {
 		slog.Error("Failed to get current branch", "repository", repoName, "error", err)
 		return err
 	}
+	if unborn && repo.Empty {
+		slog.Debug("Repository is still empty", "repository", repoName)
+		return nil
+	}
+	// Clones of empty repositories and detached HEADs are put back on a branch
+	reattach := unborn || branchName == "HEAD"
+
+	recordedDefault, err := m.operations.GetRemoteDefaultBranch(gitRepo)
+	if err != nil {
+		slog.Error("Failed to get default branch", "repository", repoName, "error", err)
+		return err
+	}
+	previousDefault := recordedDefault
+	if previousDefault == "" {
+		// Clones made before the default branch was recorded
+		previousDefault = branchName
+	}
+	followDefault := repo.Branch == "" && repo.DefaultBranch != ""
+	switchBranch := !reattach && followDefault && branchName == previousDefault && branchName != repo.DefaultBranch
+
+	if !m.config.AllBranches && repo.HeadOID != "" && branchName == repo.DefaultBranch && beforeHash.String() == repo.HeadOID {
+		slog.Debug("Repository is up to date", "repository", repoName, "head", repo.HeadOID)
+		return nil
+	}
+
+	if switchBranch && m.config.SingleBranch {
+		if err := setSingleBranch(gitRepo, repo.DefaultBranch); err != nil {
+			slog.Error("Failed to configure single branch", "repository", repoName, "error", err)
+			return err
+		}
+	}
+
+	// The remote branch as last fetched tells local commits apart from
+	// commits the remote no longer has
+	var tracked plumbing.Hash
+	if !unborn {
+		tracked, _ = m.operations.GetRemoteHash(gitRepo, branchName)
+	}
+	remoteBranches, err := snapshotRefs(gitRepo, "refs/remotes/origin/")
+	if err != nil {
+		slog.Error("Failed to list remote branches", "repository", repoName, "error", err)
+		return err
+	}
 
-	if err := m.operations.FetchRepository(gitRepo, repoName, token); err != nil {
+	if err := m.operations.FetchRepository(gitRepo, repoName, auth); err != nil {
+		if unborn && errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			slog.Info("Repository is still empty", "repository", repoName)
+			return nil
+		}
 		slog.Error("Failed to fetch updates", "repository", repoName, "error", err)
 		return err
 	}
 
+	now := time.Now()
+	if err := m.backupOverwritten(gitRepo, repoName, remoteBranches, "refs/remotes/origin/", now); err != nil {
+		return err
+	}
+
+	// Local commits on top of a remote branch that did not change leave
+	// nothing to update
+	ahead := false
+	if !unborn {
+		remoteHash, _ := m.operations.GetRemoteHash(gitRepo, branchName)
+		if !switchBranch && !reattach && remoteHash == tracked && remoteHash != beforeHash {
+			behind, err := isShallowAncestor(gitRepo, beforeHash, remoteHash)
+			if err != nil {
+				slog.Error("Failed to compare with remote branch", "repository", repoName, "error", err)
+				return err
+			}
+			ahead = !behind
+		}
+		if !ahead && (switchBranch || reattach || remoteHash != beforeHash) {
+			changes, err := m.operations.GetLocalChanges(gitRepo, tracked)
+			if err != nil {
+				slog.Error("Failed to check for local changes", "repository", repoName, "error", err)
+				return err
+			}
+			if changes.Any() {
+				target := beforeHash
+				if changes.Commits && !remoteHash.IsZero() {
+					target = remoteHash
+				}
+				done, err := m.handleLocalChanges(gitRepo, repoPath, repoName, cloneURL, repo, auth, changes, target)
+				if done || err != nil {
+					return err
+				}
+				beforeHash = target
+			}
+		}
+	}
+
+	if reattach {
+		branchName, beforeHash, err = m.reattachHead(gitRepo, repoName, repo, recordedDefault, beforeHash)
+		if err != nil {
+			slog.Error("Failed to check out branch", "repository", repoName, "error", err)
+			return err
+		}
+	}
+
+	if switchBranch {
+		newHash, err := m.operations.SwitchBranch(gitRepo, repo.DefaultBranch)
+		if err != nil {
+			slog.Error("Failed to switch to new default branch", "repository", repoName, "branch", repo.DefaultBranch, "error", err)
+			return err
+		}
+		slog.Info("Switched to new default branch", "repository", repoName, "from", branchName, "to", repo.DefaultBranch)
+		m.recordBranchSwitch(repoName, branchName, repo.DefaultBranch)
+		branchName, beforeHash = repo.DefaultBranch, newHash
+	}
+	if followDefault && recordedDefault != repo.DefaultBranch {
+		if err := m.operations.SetRemoteDefaultBranch(gitRepo, repo.DefaultBranch); err != nil {
+			slog.Error("Failed to record default branch", "repository", repoName, "error", err)
+			return err
+		}
+	}
+
 	remoteHash, err := m.operations.GetRemoteHash(gitRepo, branchName)
 	if err != nil {
 		slog.Error("Failed to get remote hash", "repository", repoName, "error", err)
 		return err
 	}
 
-	if beforeHash != remoteHash {
+	if ahead {
+		slog.Debug("Remote branch is unchanged, keeping local commits", "repository", repoName, "branch", branchName)
+	} else if beforeHash != remoteHash {
 		startTime := time.Now()
-		err := m.operations.PullRepository(gitRepo, repoName, token)
+		err := m.operations.PullRepository(gitRepo, repoName, auth)
 		
-		// Check for non-fast-forward error
+		// Rewritten remote history replaces the branch, whose previous tip is
+		// kept unless the fetch already saved it with the remote branch
 		var nonFastForwardErr *NonFastForwardError
 		if errors.As(err, &nonFastForwardErr) {
-			slog.Warn("Encountered non-fast-forward update error, will delete and re-clone repository",
+			slog.Warn("Remote history was rewritten, resetting branch",
 				"repository", repoName,
+				"branch", branchName,
 				"error", err)
-			
-			// Close the repository to release any locks
-			m.repoMutex.Lock()
-			delete(m.repositories, repoName)
-			m.repoMutex.Unlock()
-			
-			// Delete the repository folder
-			slog.Info("Deleting repository folder", "repository", repoName, "path", repoPath)
-			if err := os.RemoveAll(repoPath); err != nil {
-				slog.Error("Failed to delete repository folder", "repository", repoName, "path", repoPath, "error", err)
-				return fmt.Errorf("error deleting repository folder: %w", err)
+
+			if err := m.keepBranchTip(gitRepo, repoName, branchName, beforeHash, tracked, now); err != nil {
+				slog.Error("Failed to save backup", "repository", repoName, "error", err)
+				return err
 			}
-			
-			// Clone the repository from scratch
-			cloneURL := strings.Replace(authURL, fmt.Sprintf("%s@", token), "", 1)
-			slog.Info("Re-cloning repository", "repository", repoName, "url", cloneURL)
-			
-			cloneStartTime := time.Now()
-			if err := m.cloneRepository(repoPath, cloneURL, repoName, token); err != nil {
-				slog.Error("Failed to re-clone repository", "repository", repoName, "error", err)
+			if err := m.operations.ResetBranch(gitRepo, remoteHash); err != nil {
+				slog.Error("Failed to reset branch", "repository", repoName, "error", err)
 				return err
 			}
-			cloneEndTime := time.Now()
-			
-			slog.Info("Successfully re-cloned repository after non-fast-forward error",
-				"repository", repoName,
-				"elapsed_time", cloneEndTime.Sub(cloneStartTime))
-				
-			return nil
 		} else if err != nil {
 			slog.Error("Failed to pull updates", "repository", repoName, "error", err)
 			return err
@@ -131,18 +277,235 @@ func (m *Manager) updateRepository(repoPath, repoName, authURL, token string) er
 			"elapsed_time", endTime.Sub(startTime))
 	}
 
+	if m.config.AllBranches {
+		updated, err := m.operations.TrackRemoteBranches(gitRepo, repoName)
+		if err != nil {
+			slog.Error("Failed to update branches", "repository", repoName, "error", err)
+			return err
+		}
+		if updated > 0 {
+			slog.Info("Updated branches", "repository", repoName, "count", updated)
+		}
+	}
+
+	return nil
+}
+
+// keepBranchTip saves the tip of a branch about to be reset, unless it is
+// within the remote branch as last fetched, which the fetch already kept
+func (m *Manager) keepBranchTip(gitRepo *git.Repository, repoName, branch string, tip, tracked plumbing.Hash, now time.Time) error {
+	if !tracked.IsZero() {
+		kept, err := isShallowAncestor(gitRepo, tip, tracked)
+		if err != nil {
+			return fmt.Errorf("error comparing with remote branch: %w", err)
+		}
+		if kept {
+			return nil
+		}
+	}
+
+	ref, err := m.operations.SaveBackup(gitRepo, branch, tip, now)
+	if err != nil {
+		return err
+	}
+	m.preservedBranches.Add(1)
+	slog.Info("Saved previous branch tip", "repository", repoName, "ref", ref)
+	return nil
+}
+
+// backupOverwritten saves the previous tips of the branches in before that
+// the fetch rewrote or deleted
+func (m *Manager) backupOverwritten(gitRepo *git.Repository, repoName string, before map[plumbing.ReferenceName]plumbing.Hash, prefix string, now time.Time) error {
+	saved, err := m.operations.BackupOverwritten(gitRepo, before, prefix, now)
+	if err != nil {
+		slog.Error("Failed to save backups of rewritten branches", "repository", repoName, "error", err)
+		return err
+	}
+	if len(saved) > 0 {
+		m.preservedBranches.Add(int64(len(saved)))
+		slog.Warn("Saved previous tips of rewritten branches", "repository", repoName, "refs", saved)
+	}
+	return nil
+}
+
+// pruneBackups deletes the backups older than the backup retention. Failing
+// to prune does not fail the update.
+func (m *Manager) pruneBackups(gitRepo *git.Repository, repoName string) {
+	if m.config.BackupRetention <= 0 {
+		return
+	}
+	pruned, err := m.operations.PruneBackups(gitRepo, time.Now().Add(-m.config.BackupRetention))
+	if err != nil {
+		slog.Warn("Failed to prune backups", "repository", repoName, "error", err)
+		return
+	}
+	if pruned > 0 {
+		slog.Info("Pruned backups", "repository", repoName, "count", pruned)
+	}
+}
+
+// handleLocalChanges applies the local changes policy to a clone that an
+// update would overwrite. It reports whether the update is done; otherwise the
+// changes were stashed and the worktree reset to target.
+func (m *Manager) handleLocalChanges(gitRepo *git.Repository, repoPath, repoName, cloneURL string, repo *provider.Repository, auth transport.AuthMethod, changes LocalChanges, target plumbing.Hash) (bool, error) {
+	switch m.config.LocalChanges {
+	case config.LocalChangesStash:
+		ref, err := m.operations.StashLocalChanges(gitRepo, target)
+		if err != nil {
+			slog.Error("Failed to stash local changes", "repository", repoName, "error", err)
+			return true, err
+		}
+		slog.Warn("Stashed local changes", "repository", repoName, "changes", changes.String(), "ref", ref)
+		m.recordLocalChanges(repoName, fmt.Sprintf("stashed to %s", ref), changes)
+		return false, nil
+
+	case config.LocalChangesQuarantine:
+		quarantinePath := m.config.StatePath("quarantine", repoName+"-"+time.Now().UTC().Format(timestampFormat))
+		if err := os.MkdirAll(filepath.Dir(quarantinePath), 0755); err != nil {
+			return true, fmt.Errorf("error creating quarantine directory: %w", err)
+		}
+		m.forgetRepository(repoName)
+		if err := os.Rename(repoPath, quarantinePath); err != nil {
+			slog.Error("Failed to quarantine repository", "repository", repoName, "error", err)
+			return true, fmt.Errorf("error moving repository to quarantine: %w", err)
+		}
+		slog.Warn("Quarantined repository with local changes", "repository", repoName, "changes", changes.String(), "path", quarantinePath)
+		m.recordLocalChanges(repoName, fmt.Sprintf("quarantined to %s", quarantinePath), changes)
+		return true, m.cloneRepository(repoPath, cloneURL, repoName, repo.Branch, auth)
+
+	default:
+		slog.Warn("Skipped update of repository with local changes", "repository", repoName, "changes", changes.String())
+		m.recordLocalChanges(repoName, "skipped", changes)
+		return true, nil
+	}
+}
+
+// reattachHead checks out the branch of a clone with a detached or unborn
+// HEAD: the manifest branch, else the default branch. A detached commit that
+// is not on the branch is logged, so it can be recovered.
+func (m *Manager) reattachHead(gitRepo *git.Repository, repoName string, repo *provider.Repository, recordedDefault string, detached plumbing.Hash) (string, plumbing.Hash, error) {
+	branch := firstNonEmpty(repo.Branch, repo.DefaultBranch, recordedDefault)
+	if branch == "" {
+		// An unborn HEAD still names the branch its first commit creates
+		if ref, err := gitRepo.Storer.Reference(plumbing.HEAD); err == nil && ref.Type() == plumbing.SymbolicReference {
+			branch = ref.Target().Short()
+		}
+	}
+	if branch == "" {
+		return "", plumbing.ZeroHash, fmt.Errorf("cannot determine the branch to check out")
+	}
+
+	if !detached.IsZero() {
+		remoteHash, err := m.operations.GetRemoteHash(gitRepo, branch)
+		if err != nil {
+			return "", plumbing.ZeroHash, err
+		}
+		onBranch, err := isShallowAncestor(gitRepo, detached, remoteHash)
+		if err != nil {
+			return "", plumbing.ZeroHash, fmt.Errorf("error comparing detached HEAD: %w", err)
+		}
+		if !onBranch {
+			slog.Warn("Detached HEAD commit is not on the branch", "repository", repoName, "commit", detached.String(), "branch", branch)
+		}
+	}
+
+	hash, err := m.operations.SwitchBranch(gitRepo, branch)
+	if err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+	slog.Info("Checked out branch", "repository", repoName, "branch", branch)
+	return branch, hash, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// updateMirror updates a bare mirror with a forced, pruning fetch of every
+// ref. Rewritten history overwrites the refs, after their previous tips are
+// saved, so mirrors are never re-cloned.
+func (m *Manager) updateMirror(repoPath, repoName, cloneURL string, auth transport.AuthMethod) error {
+	gitRepo, err := m.openRepository(repoPath, repoName)
+	if err != nil {
+		slog.Error("Failed to open repository", "repository", repoName, "error", err)
+		return err
+	}
+
+	if err := checkMirror(gitRepo, true); err != nil {
+		slog.Error("Failed to update mirror", "repository", repoName, "error", err)
+		return err
+	}
+
+	if err := m.syncRemoteURL(gitRepo, repoName, cloneURL); err != nil {
+		slog.Error("Failed to update remote URL", "repository", repoName, "error", err)
+		return err
+	}
+	m.pruneBackups(gitRepo, repoName)
+
+	// The mirror fetch prunes every ref the remote does not have, including
+	// the ones ghloner keeps, so they are restored afterwards
+	branches, err := snapshotRefs(gitRepo, "refs/heads/")
+	if err != nil {
+		slog.Error("Failed to list branches", "repository", repoName, "error", err)
+		return err
+	}
+	stateRefs, err := snapshotRefs(gitRepo, stateRefPrefix)
+	if err != nil {
+		slog.Error("Failed to list backups", "repository", repoName, "error", err)
+		return err
+	}
+
+	startTime := time.Now()
+	updated, err := m.operations.FetchMirror(gitRepo, repoName, auth)
+	if err != nil {
+		slog.Error("Failed to fetch mirror", "repository", repoName, "error", err)
+		return err
+	}
+	if err := restoreRefs(gitRepo, stateRefs); err != nil {
+		slog.Error("Failed to restore backups", "repository", repoName, "error", err)
+		return err
+	}
+	if err := m.backupOverwritten(gitRepo, repoName, branches, "refs/heads/", startTime); err != nil {
+		return err
+	}
+	if updated {
+		slog.Info("Updated mirror", "repository", repoName, "elapsed_time", time.Since(startTime))
+	}
+
+	return nil
+}
+
+// checkMirror returns an error when an existing clone is a bare mirror and
+// mirror mode is off, or the other way around
+func checkMirror(gitRepo *git.Repository, mirror bool) error {
+	cfg, err := gitRepo.Config()
+	if err != nil {
+		return fmt.Errorf("error reading repository config: %w", err)
+	}
+
+	switch {
+	case mirror && !cfg.Core.IsBare:
+		return fmt.Errorf("existing clone has a working tree, remove it or use another output directory for mirrors")
+	case !mirror && cfg.Core.IsBare:
+		return fmt.Errorf("existing clone is a bare mirror, use --mirror or another output directory")
+	}
 	return nil
 }
 
 // cloneRepository clones a new repository
-func (m *Manager) cloneRepository(repoPath, cloneURL, repoName, token string) error {
+func (m *Manager) cloneRepository(repoPath, cloneURL, repoName, branch string, auth transport.AuthMethod) error {
 	var cloneErr error
 	var attemptCount int
 	
 	for attemptCount = 1; attemptCount <= m.config.RetryCount; attemptCount++ {
 		startTime := time.Now()
 		
-		cloneErr = m.operations.CloneRepository(repoPath, cloneURL, repoName, token)
+		cloneErr = m.cloneStaged(repoPath, cloneURL, repoName, branch, auth)
 		
 		endTime := time.Now()
 
@@ -184,6 +547,155 @@ func (m *Manager) cloneRepository(repoPath, cloneURL, repoName, token string) er
 	return nil
 }
 
+// cloneStaged clones into a new directory below the staging directory and
+// moves the clone into place once it is complete, so an interrupted clone
+// never appears as a repository in the output directory
+func (m *Manager) cloneStaged(repoPath, cloneURL, repoName, branch string, auth transport.AuthMethod) error {
+	if err := os.MkdirAll(m.config.StagingDir(), 0755); err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+	stagingPath, err := os.MkdirTemp(m.config.StagingDir(), strings.ReplaceAll(repoName, "/", "_")+"-*")
+	if err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingPath)
+
+	if m.config.Mirror {
+		err = m.operations.CloneMirror(stagingPath, cloneURL, auth)
+	} else {
+		err = m.operations.CloneRepository(stagingPath, cloneURL, repoName, branch, auth)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(stagingPath, repoPath); err != nil {
+		return fmt.Errorf("error moving clone into place: %w", err)
+	}
+	return nil
+}
+
+// remoteURL returns the URL to clone the repository from. With SSH
+// authentication, the SSH URL is used when the provider reports one.
+func (m *Manager) remoteURL(repo *provider.Repository) string {
+	if m.sshAuth != nil && repo.SSHURL != "" {
+		return repo.SSHURL
+	}
+	return repo.CloneURL
+}
+
+// authMethod returns the authentication for a remote: the SSH authentication
+// for SSH remotes, and the token for HTTP remotes
+func (m *Manager) authMethod(remoteURL, token string) transport.AuthMethod {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err == nil && endpoint.Protocol == "ssh" {
+		if m.sshAuth == nil {
+			// go-git falls back to ssh-agent and the default known_hosts
+			return nil
+		}
+		return m.sshAuth.authMethod(endpoint)
+	}
+	return basicAuth(token)
+}
+
+// ScrubbedRemotes returns the number of remotes whose embedded credentials
+// were removed
+func (m *Manager) ScrubbedRemotes() int {
+	return int(m.scrubbedRemotes.Load())
+}
+
+// PreservedBranches returns the number of branch tips saved before they were
+// overwritten by rewritten history
+func (m *Manager) PreservedBranches() int {
+	return int(m.preservedBranches.Load())
+}
+
+// BranchSwitches returns the clones that switched to a new default branch,
+// as "repository: old -> new"
+func (m *Manager) BranchSwitches() []string {
+	m.reportMutex.Lock()
+	defer m.reportMutex.Unlock()
+
+	return append([]string(nil), m.branchSwitches...)
+}
+
+func (m *Manager) recordBranchSwitch(repoName, from, to string) {
+	m.reportMutex.Lock()
+	defer m.reportMutex.Unlock()
+
+	m.branchSwitches = append(m.branchSwitches, fmt.Sprintf("%s: %s -> %s", repoName, from, to))
+}
+
+// LocalChangeReports returns the clones with local changes an update would
+// have overwritten, and what was done with them
+func (m *Manager) LocalChangeReports() []string {
+	m.reportMutex.Lock()
+	defer m.reportMutex.Unlock()
+
+	return append([]string(nil), m.localChangeReports...)
+}
+
+func (m *Manager) recordLocalChanges(repoName, action string, changes LocalChanges) {
+	m.reportMutex.Lock()
+	defer m.reportMutex.Unlock()
+
+	m.localChangeReports = append(m.localChangeReports, fmt.Sprintf("%s: %s (%s)", repoName, action, changes))
+}
+
+// syncRemoteURL points the origin remote at the clone URL. Credentials are
+// never stored in the remote, since authentication is passed with each fetch,
+// and tokens left in the remote by earlier versions are removed.
+func (m *Manager) syncRemoteURL(gitRepo *git.Repository, repoName, cloneURL string) error {
+	current, err := m.operations.GetRemoteURL(gitRepo)
+	if err != nil {
+		return err
+	}
+
+	if current == cloneURL {
+		return nil
+	}
+	if err := m.operations.UpdateRemoteURL(gitRepo, cloneURL); err != nil {
+		return err
+	}
+	if hasCredentials(current) && !hasCredentials(cloneURL) {
+		m.scrubbedRemotes.Add(1)
+		slog.Info("Removed credentials from remote URL", "repository", repoName)
+	}
+	return nil
+}
+
+// splitCredentials removes the user information from an HTTP remote URL and
+// returns it as the authentication for the remote, or nil when there is none
+func splitCredentials(remoteURL string) (string, transport.AuthMethod) {
+	if !hasCredentials(remoteURL) {
+		return remoteURL, nil
+	}
+	u, _ := url.Parse(remoteURL)
+	password, _ := u.User.Password()
+	auth := &http.BasicAuth{Username: u.User.Username(), Password: password}
+	u.User = nil
+	return u.String(), auth
+}
+
+// hasCredentials reports whether an HTTP remote URL carries user information.
+// The user of SSH URLs, such as git@, is not a credential.
+func hasCredentials(remoteURL string) bool {
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.User != nil
+}
+
+// forgetRepository drops a repository from the cache, before its directory
+// is removed or moved
+func (m *Manager) forgetRepository(repoName string) {
+	m.repoMutex.Lock()
+	defer m.repoMutex.Unlock()
+
+	delete(m.repositories, repoName)
+}
+
 // openRepository opens a Git repository and caches it
 func (m *Manager) openRepository(repoPath string, repoName string) (*git.Repository, error) {
 	m.repoMutex.Lock()
@@ -4,11 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	nethttp "net/http"
 	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
@@ -31,6 +35,14 @@ func IsNonFastForwardError(err error) bool {
 // Operations provides common Git operations
 type Operations struct {
 	retryCount int
+
+	// depth limits the history of clones and updates to this many commits,
+	// zero for the full history
+	depth int
+	// singleBranch clones only the checked out branch
+	singleBranch bool
+	// allBranches creates a local branch for every remote branch of clones
+	allBranches bool
 }
 
 // NewOperations creates a new Git operations instance
@@ -67,6 +79,20 @@ func (o *Operations) RunWithRetry(repoName string, operation string, fn func() e
 	return nil
 }
 
+// GetRemoteURL returns the URL of the origin remote
+func (o *Operations) GetRemoteURL(repo *git.Repository) (string, error) {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return "", fmt.Errorf("error getting remote: %w", err)
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", nil
+	}
+	return urls[0], nil
+}
+
 // UpdateRemoteURL updates the remote URL for a repository
 func (o *Operations) UpdateRemoteURL(repo *git.Repository, remoteURL string) error {
 	remote, err := repo.Remote("origin")
@@ -106,15 +132,13 @@ func (o *Operations) GetRepositoryHead(repo *git.Repository) (string, plumbing.H
 }
 
 // FetchRepository fetches updates from the remote repository
-func (o *Operations) FetchRepository(repo *git.Repository, repoName, token string) error {
+func (o *Operations) FetchRepository(repo *git.Repository, repoName string, auth transport.AuthMethod) error {
 	return o.RunWithRetry(repoName, "fetching updates for", func() error {
 		err := repo.Fetch(&git.FetchOptions{
 			RemoteName: "origin",
-			Auth: &http.BasicAuth{
-				Username: "anything_except_an_empty_string",
-				Password: token,
-			},
-			Force: true,
+			Auth:       auth,
+			Force:      true,
+			Depth:      o.depth,
 		})
 
 		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
@@ -125,6 +149,35 @@ func (o *Operations) FetchRepository(repo *git.Repository, repoName, token strin
 	})
 }
 
+// mirrorRefSpec maps every ref of the remote to the same ref in the mirror
+const mirrorRefSpec = gitconfig.RefSpec("+refs/*:refs/*")
+
+// FetchMirror updates every ref of a mirror from the remote. Refs are
+// overwritten even when history was rewritten, and refs deleted on the remote
+// are pruned. It reports whether any ref changed.
+func (o *Operations) FetchMirror(repo *git.Repository, repoName string, auth transport.AuthMethod) (bool, error) {
+	updated := false
+	err := o.RunWithRetry(repoName, "fetching mirror of", func() error {
+		err := repo.Fetch(&git.FetchOptions{
+			RemoteName: "origin",
+			RefSpecs:   []gitconfig.RefSpec{mirrorRefSpec},
+			Auth:       auth,
+			Force:      true,
+			Prune:      true,
+		})
+
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching: %w", err)
+		}
+		updated = true
+		return nil
+	})
+	return updated, err
+}
+
 // GetRemoteHash gets the hash of the remote branch
 func (o *Operations) GetRemoteHash(repo *git.Repository, branchName string) (plumbing.Hash, error) {
 	remoteBranchRef := plumbing.NewRemoteReferenceName("origin", branchName)
@@ -137,19 +190,28 @@ func (o *Operations) GetRemoteHash(repo *git.Repository, branchName string) (plu
 }
 
 // PullRepository pulls updates from the remote repository
-func (o *Operations) PullRepository(repo *git.Repository, repoName, token string) error {
+func (o *Operations) PullRepository(repo *git.Repository, repoName string, auth transport.AuthMethod) error {
+	if o.depth > 0 {
+		return o.fastForwardShallow(repo, repoName)
+	}
+
 	return o.RunWithRetry(repoName, "pulling updates for", func() error {
 		w, err := repo.Worktree()
 		if err != nil {
 			return fmt.Errorf("error getting worktree: %w", err)
 		}
 
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("error getting HEAD: %w", err)
+		}
+
+		// Pull the checked out branch, rather than the default branch of the remote
 		err = w.Pull(&git.PullOptions{
-			RemoteName: "origin",
-			Auth: &http.BasicAuth{
-				Username: "anything_except_an_empty_string",
-				Password: token,
-			},
+			RemoteName:    "origin",
+			ReferenceName: head.Name(),
+			Auth:          auth,
+			Depth:         o.depth,
 		})
 
 		if err != nil {
@@ -171,15 +233,110 @@ func (o *Operations) PullRepository(repo *git.Repository, repoName, token string
 	})
 }
 
-// CloneRepository clones a repository
-func (o *Operations) CloneRepository(repoPath, cloneURL, repoName, token string) error {
-	_, err := git.PlainClone(repoPath, false, &git.CloneOptions{
-		URL: cloneURL,
-		Auth: &http.BasicAuth{
-			Username: "anything_except_an_empty_string",
-			Password: token,
-		},
-	})
+// fastForwardShallow moves the checked out branch of a shallow clone to the
+// fetched remote branch. go-git cannot pull into shallow clones, as it walks
+// history past the shallow boundary. Unless the current commit is proven to be
+// within the fetched history, the update is reported as non-fast-forward, so
+// that the current commit is kept before the branch is reset.
+func (o *Operations) fastForwardShallow(repo *git.Repository, repoName string) error {
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("error getting HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("error pulling: HEAD is not a branch")
+	}
+
+	remoteHash, err := o.GetRemoteHash(repo, head.Name().Short())
+	if err != nil {
+		return err
+	}
+	if remoteHash == head.Hash() {
+		return nil
+	}
+
+	reachable, complete, err := shallowAncestry(repo, head.Hash(), remoteHash)
+	if err != nil {
+		return fmt.Errorf("error pulling: %w", err)
+	}
+	if !reachable {
+		reason := "is not in the history of " + remoteHash.String()[:8]
+		if !complete {
+			reason = "is not within the shallow history"
+		}
+		return &NonFastForwardError{
+			RepoName: repoName,
+			Err:      fmt.Errorf("non-fast-forward update: %s %s", head.Hash().String()[:8], reason),
+		}
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), remoteHash)); err != nil {
+		return fmt.Errorf("error updating branch: %w", err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("error getting worktree: %w", err)
+	}
+	if err := w.Reset(&git.ResetOptions{Mode: git.MergeReset, Commit: remoteHash}); err != nil {
+		return fmt.Errorf("error updating worktree: %w", err)
+	}
+	return nil
+}
+
+// isShallowAncestor reports whether ancestor is reachable from commit through
+// the commits present in the repository. History beyond the shallow boundary
+// is missing, so the walk ends there.
+func isShallowAncestor(repo *git.Repository, ancestor, commit plumbing.Hash) (bool, error) {
+	reachable, _, err := shallowAncestry(repo, ancestor, commit)
+	return reachable, err
+}
+
+// shallowAncestry reports whether ancestor is reachable from commit, and
+// whether the walk saw the whole history of commit. When it ended at the
+// shallow boundary without reaching ancestor, ancestry is unknown.
+func shallowAncestry(repo *git.Repository, ancestor, commit plumbing.Hash) (reachable, complete bool, err error) {
+	complete = true
+	seen := map[plumbing.Hash]bool{commit: true}
+	queue := []plumbing.Hash{commit}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if hash == ancestor {
+			return true, true, nil
+		}
+
+		c, err := repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			complete = false
+			continue
+		}
+		if err != nil {
+			return false, false, err
+		}
+		for _, parent := range c.ParentHashes {
+			if !seen[parent] {
+				seen[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return false, complete, nil
+}
+
+// CloneRepository clones a repository, checking out branch when it is set
+func (o *Operations) CloneRepository(repoPath, cloneURL, repoName, branch string, auth transport.AuthMethod) error {
+	options := &git.CloneOptions{
+		URL:          cloneURL,
+		Auth:         auth,
+		Depth:        o.depth,
+		SingleBranch: o.singleBranch,
+	}
+	if branch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(branch)
+	}
+
+	repo, err := git.PlainClone(repoPath, false, options)
 	
 	if err != nil {
 		if strings.Contains(err.Error(), "remote repository is empty") {
@@ -187,6 +344,99 @@ func (o *Operations) CloneRepository(repoPath, cloneURL, repoName, token string)
 		}
 		return fmt.Errorf("error cloning repository: %w", err)
 	}
+
+	if o.singleBranch && branch == "" {
+		if err := trackSingleBranch(repo); err != nil {
+			return fmt.Errorf("error configuring single branch: %w", err)
+		}
+	}
+
+	// Remember the default branch, to notice when the remote changes it
+	if head, err := repo.Head(); branch == "" && err == nil && head.Name().IsBranch() {
+		if err := o.SetRemoteDefaultBranch(repo, head.Name().Short()); err != nil {
+			return err
+		}
+	}
+
+	if o.allBranches {
+		if _, err := o.TrackRemoteBranches(repo, repoName); err != nil {
+			return err
+		}
+	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// ResetBranch moves the checked out branch to commit, discarding its
+// history, and resets the worktree to it
+func (o *Operations) ResetBranch(repo *git.Repository, commit plumbing.Hash) error {
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("error getting worktree: %w", err)
+	}
+	if err := w.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("error resetting branch: %w", err)
+	}
+	return nil
+}
+
+// trackSingleBranch makes a single branch clone of the remote HEAD track the
+// branch HEAD pointed to, as git does. go-git tracks HEAD itself, which leaves
+// no remote branch to update from.
+func trackSingleBranch(repo *git.Repository) error {
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return nil
+	}
+	branch := head.Name().Short()
+
+	if err := setSingleBranch(repo, branch); err != nil {
+		return err
+	}
+	if err := repo.Storer.RemoveReference(remoteHEAD); err != nil {
+		return err
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", branch), head.Hash()))
+}
+
+// InstallHTTPTransport makes git operations over HTTP and HTTPS use the given
+// transport, such as one with proxy and CA settings. It applies to the whole
+// process.
+func InstallHTTPTransport(rt nethttp.RoundTripper) {
+	c := http.NewClient(&nethttp.Client{Transport: rt})
+	client.InstallProtocol("https", c)
+	client.InstallProtocol("http", c)
+}
+
+// CloneMirror clones a repository as a bare mirror of all its refs
+func (o *Operations) CloneMirror(repoPath, cloneURL string, auth transport.AuthMethod) error {
+	_, err := git.PlainClone(repoPath, true, &git.CloneOptions{
+		URL:    cloneURL,
+		Auth:   auth,
+		Mirror: true,
+	})
+
+	if err != nil {
+		if strings.Contains(err.Error(), "remote repository is empty") {
+			return fmt.Errorf("repository is empty: %w", err)
+		}
+		return fmt.Errorf("error cloning repository: %w", err)
+	}
+
+	return nil
+}
+
+// basicAuth returns the token as HTTP basic authentication. Without a token
+// no authentication is sent, as for public or local remotes.
+func basicAuth(token string) transport.AuthMethod {
+	if token == "" {
+		return nil
+	}
+	return &http.BasicAuth{
+		Username: "anything_except_an_empty_string",
+		Password: token,
+	}
+}
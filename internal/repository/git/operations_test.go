@@ -2,11 +2,16 @@ package git
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/test/helpers"
 )
 
 func TestIsNonFastForwardError(t *testing.T) {
@@ -183,4 +188,28 @@ func TestRunWithRetry_BackoffTiming(t *testing.T) {
 		assert.True(t, delay2 >= 4900*time.Millisecond && delay2 <= 5100*time.Millisecond,
 			"Second retry delay should be ~5s, got %v", delay2)
 	}
-}
\ No newline at end of file
+}
+
+func TestInstallHTTPTransport(t *testing.T) {
+	root := t.TempDir()
+	helpers.CreateTestRepo(t, filepath.Join(root, "remote"))
+	server := helpers.StartHTTPSGitServer(t, root)
+	cloneURL := server.URL + "/remote"
+
+	ops := NewOperations(1)
+
+	// The test CA is not trusted by default
+	err := ops.CloneRepository(filepath.Join(t.TempDir(), "untrusted"), cloneURL, "remote", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+
+	transport, err := config.NewHTTPTransport(&config.Config{CABundle: helpers.WriteCABundle(t, server)})
+	require.NoError(t, err)
+	InstallHTTPTransport(transport)
+	t.Cleanup(func() {
+		client.InstallProtocol("https", http.DefaultClient)
+		client.InstallProtocol("http", http.DefaultClient)
+	})
+
+	require.NoError(t, ops.CloneRepository(filepath.Join(t.TempDir(), "trusted"), cloneURL, "remote", "", nil))
+}
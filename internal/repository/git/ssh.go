@@ -0,0 +1,78 @@
+package git
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/skeema/knownhosts"
+	"github.com/truemilk/ghloner/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshUser is the user of SSH remotes on GitHub, GitLab and Gitea, used when
+// the remote URL does not name one
+const sshUser = "git"
+
+// SSHAuth authenticates SSH remotes with a key file or ssh-agent, and
+// verifies host keys against known_hosts files
+type SSHAuth struct {
+	signers    func() ([]ssh.Signer, error)
+	knownHosts *knownhosts.HostKeyDB
+}
+
+// NewSSHAuth creates the SSH authentication from the configured key file, or
+// from ssh-agent when no key file is set. Host keys are verified against the
+// configured known_hosts file, or the default ones of OpenSSH.
+func NewSSHAuth(cfg *config.Config) (*SSHAuth, error) {
+	var files []string
+	if cfg.SSHKnownHosts != "" {
+		files = append(files, cfg.SSHKnownHosts)
+	}
+	knownHosts, err := gitssh.NewKnownHostsDb(files...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading known_hosts: %w", err)
+	}
+
+	auth := &SSHAuth{knownHosts: knownHosts}
+	if cfg.SSHKey != "" {
+		keys, err := gitssh.NewPublicKeysFromFile(sshUser, cfg.SSHKey, cfg.SSHKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error loading SSH key %s: %w", cfg.SSHKey, err)
+		}
+		auth.signers = func() ([]ssh.Signer, error) { return []ssh.Signer{keys.Signer}, nil }
+	} else {
+		agent, err := gitssh.NewSSHAgentAuth(sshUser)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to ssh-agent (set --ssh-key to use a key file): %w", err)
+		}
+		auth.signers = agent.Callback
+	}
+	return auth, nil
+}
+
+// authMethod returns the authentication for a remote endpoint, as the user in
+// its URL. Only the host key algorithms known for the host are negotiated, so
+// a host with several keys is verified against the one in known_hosts.
+func (a *SSHAuth) authMethod(endpoint *transport.Endpoint) transport.AuthMethod {
+	user := endpoint.User
+	if user == "" {
+		user = sshUser
+	}
+	port := endpoint.Port
+	if port == 0 {
+		port = 22
+	}
+	hostWithPort := net.JoinHostPort(endpoint.Host, strconv.Itoa(port))
+
+	return &gitssh.PublicKeysCallback{
+		User:     user,
+		Callback: a.signers,
+		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
+			HostKeyCallback:   a.knownHosts.HostKeyCallback(),
+			HostKeyAlgorithms: a.knownHosts.HostKeyAlgorithms(hostWithPort),
+		},
+	}
+}
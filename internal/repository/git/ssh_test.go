@@ -0,0 +1,220 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+	"github.com/truemilk/ghloner/test/helpers"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// writeKnownHosts writes a known_hosts file with the given entry
+func writeKnownHosts(t *testing.T, line string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0600))
+	return path
+}
+
+// writeSSHKey writes a new Ed25519 private key, encrypted with passphrase
+// unless it is empty, and returns its path and the key
+func writeSSHKey(t *testing.T, passphrase string) (string, ed25519.PrivateKey) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	}
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+	return path, key
+}
+
+func publicKey(t *testing.T, key ed25519.PrivateKey) ssh.PublicKey {
+	t.Helper()
+
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer.PublicKey()
+}
+
+func TestProcessRepository_SSH(t *testing.T) {
+	keyPath, key := writeSSHKey(t, "secret")
+	server := helpers.StartSSHGitServer(t, publicKey(t, key))
+
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	remote := helpers.CreateTestRepo(t, remotePath)
+
+	cfg := &config.Config{
+		OutputDir:        t.TempDir(),
+		RetryCount:       1,
+		GitProtocol:      config.GitProtocolSSH,
+		SSHKey:           keyPath,
+		SSHKeyPassphrase: "secret",
+		SSHKnownHosts:    writeKnownHosts(t, server.KnownHostsLine()),
+	}
+	auth, err := NewSSHAuth(cfg)
+	require.NoError(t, err)
+	manager := NewManager(cfg)
+	manager.SetSSHAuth(auth)
+
+	repo := &provider.Repository{
+		Name:     "remote",
+		CloneURL: "https://github.com/testorg/remote.git",
+		SSHURL:   server.URL(remotePath),
+	}
+	require.NoError(t, manager.ProcessRepository(repo, "test-token"))
+
+	// Updates go through the same SSH remote
+	newHead := helpers.CommitFile(t, remote, "update.txt", "update\n")
+	require.NoError(t, manager.ProcessRepository(repo, "test-token"))
+
+	local, err := git.PlainOpen(filepath.Join(cfg.OutputDir, "remote"))
+	require.NoError(t, err)
+	head, err := local.Head()
+	require.NoError(t, err)
+	assert.Equal(t, newHead, head.Hash())
+
+	remoteURL, err := manager.operations.GetRemoteURL(local)
+	require.NoError(t, err)
+	assert.Equal(t, repo.SSHURL, remoteURL)
+}
+
+func TestProcessRepository_SSHAgent(t *testing.T) {
+	_, key := writeSSHKey(t, "")
+	server := helpers.StartSSHGitServer(t, publicKey(t, key))
+
+	// Serve an in-memory agent holding the key
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	remote := helpers.CreateTestRepo(t, remotePath)
+	head, err := remote.Head()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		OutputDir:     t.TempDir(),
+		RetryCount:    1,
+		GitProtocol:   config.GitProtocolSSH,
+		SSHKnownHosts: writeKnownHosts(t, server.KnownHostsLine()),
+	}
+	auth, err := NewSSHAuth(cfg)
+	require.NoError(t, err)
+	manager := NewManager(cfg)
+	manager.SetSSHAuth(auth)
+
+	repo := &provider.Repository{Name: "remote", SSHURL: server.URL(remotePath)}
+	require.NoError(t, manager.ProcessRepository(repo, ""))
+
+	local, err := git.PlainOpen(filepath.Join(cfg.OutputDir, "remote"))
+	require.NoError(t, err)
+	localHead, err := local.Head()
+	require.NoError(t, err)
+	assert.Equal(t, head.Hash(), localHead.Hash())
+	assert.Equal(t, plumbing.Master, localHead.Name())
+}
+
+func TestProcessRepository_SSHUser(t *testing.T) {
+	keyPath, key := writeSSHKey(t, "")
+	server := helpers.StartSSHGitServerForUser(t, publicKey(t, key), "mirror")
+
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	remote := helpers.CreateTestRepo(t, remotePath)
+	head, err := remote.Head()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		OutputDir:     t.TempDir(),
+		RetryCount:    1,
+		GitProtocol:   config.GitProtocolSSH,
+		SSHKey:        keyPath,
+		SSHKnownHosts: writeKnownHosts(t, server.KnownHostsLine()),
+	}
+	auth, err := NewSSHAuth(cfg)
+	require.NoError(t, err)
+	manager := NewManager(cfg)
+	manager.SetSSHAuth(auth)
+
+	// The user in the SSH URL replaces the default git user
+	require.Equal(t, "ssh://mirror@"+server.Addr+filepath.ToSlash(remotePath), server.URL(remotePath))
+	require.NoError(t, manager.ProcessRepository(&provider.Repository{Name: "remote", SSHURL: server.URL(remotePath)}, ""))
+
+	local, err := git.PlainOpen(filepath.Join(cfg.OutputDir, "remote"))
+	require.NoError(t, err)
+	localHead, err := local.Head()
+	require.NoError(t, err)
+	assert.Equal(t, head.Hash(), localHead.Hash())
+}
+
+func TestProcessRepository_SSHUnknownHostKey(t *testing.T) {
+	keyPath, key := writeSSHKey(t, "")
+	server := helpers.StartSSHGitServer(t, publicKey(t, key))
+
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	helpers.CreateTestRepo(t, remotePath)
+
+	// known_hosts lists another key for the server
+	other := &helpers.SSHGitServer{Addr: server.Addr, HostKey: helpers.NewSSHSigner(t).PublicKey()}
+	cfg := &config.Config{
+		OutputDir:     t.TempDir(),
+		RetryCount:    1,
+		GitProtocol:   config.GitProtocolSSH,
+		SSHKey:        keyPath,
+		SSHKnownHosts: writeKnownHosts(t, other.KnownHostsLine()),
+	}
+	auth, err := NewSSHAuth(cfg)
+	require.NoError(t, err)
+	manager := NewManager(cfg)
+	manager.SetSSHAuth(auth)
+
+	err = manager.ProcessRepository(&provider.Repository{Name: "remote", SSHURL: server.URL(remotePath)}, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key mismatch")
+	assert.NoDirExists(t, filepath.Join(cfg.OutputDir, "remote"))
+}
+
+func TestNewSSHAuth_WrongPassphrase(t *testing.T) {
+	keyPath, _ := writeSSHKey(t, "secret")
+
+	_, err := NewSSHAuth(&config.Config{
+		SSHKey:           keyPath,
+		SSHKeyPassphrase: "wrong",
+		SSHKnownHosts:    writeKnownHosts(t, ""),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error loading SSH key")
+}
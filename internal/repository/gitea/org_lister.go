@@ -0,0 +1,251 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// pageSize is the number of repositories requested per page. Gitea caps the
+// page size at its MAX_RESPONSE_ITEMS setting, 50 by default, so the size of
+// the first page returned is the page size of the remaining pages.
+const pageSize = 50
+
+// repository is the subset of the Gitea repository API response used by ghloner
+type repository struct {
+	Name          string `json:"name"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	DefaultBranch string `json:"default_branch"`
+	Private       bool   `json:"private"`
+	Internal      bool   `json:"internal"`
+	Archived      bool   `json:"archived"`
+	Fork          bool   `json:"fork"`
+	Language      string `json:"language"`
+	Size          int    `json:"size"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+// OrgLister lists the repositories of Gitea or Forgejo organizations
+type OrgLister struct {
+	httpClient *http.Client
+	baseURL    string
+	config     *config.Config
+}
+
+// NewOrgLister creates a new Gitea organization lister
+func NewOrgLister(httpClient *http.Client, cfg *config.Config) *OrgLister {
+	return &OrgLister{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(cfg.APIURL, "/"),
+		config:     cfg,
+	}
+}
+
+// ListRepositories lists the repositories of every configured organization
+func (l *OrgLister) ListRepositories(ctx context.Context) ([]*provider.Repository, error) {
+	var allRepos []*provider.Repository
+	for _, orgName := range l.config.OrgNames {
+		repos, err := l.listOrganization(ctx, orgName)
+		if err != nil {
+			return nil, fmt.Errorf("error listing repositories for %s: %w", orgName, err)
+		}
+		slog.Info("Found organization repositories", "count", len(repos), "organization", orgName)
+
+		for _, repo := range repos {
+			allRepos = append(allRepos, l.toRepository(repo, orgName))
+		}
+	}
+	return allRepos, nil
+}
+
+// listOrganization fetches the first page of an organization's repositories
+// and the remaining pages concurrently
+func (l *OrgLister) listOrganization(ctx context.Context, orgName string) ([]repository, error) {
+	startTime := time.Now()
+
+	firstPageRepos, totalCount, err := l.fetchPage(ctx, orgName, 1)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching first page of repositories: %w", err)
+	}
+
+	if totalCount < 0 {
+		return l.fetchSequentially(ctx, orgName, firstPageRepos)
+	}
+
+	perPage := len(firstPageRepos)
+	if perPage == 0 || totalCount <= perPage {
+		return firstPageRepos, nil
+	}
+	totalPages := (totalCount + perPage - 1) / perPage
+
+	slog.Info("Found multiple pages of repositories", "pages", totalPages, "workers", l.config.Workers)
+
+	type pageResult struct {
+		page  int
+		repos []repository
+		err   error
+	}
+	resultChan := make(chan pageResult, totalPages)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, l.config.Workers)
+
+	for page := 2; page <= totalPages; page++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(pageNum int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			repos, _, err := l.fetchPage(ctx, orgName, pageNum)
+			if err != nil {
+				resultChan <- pageResult{page: pageNum, err: err}
+				return
+			}
+
+			slog.Debug("Fetched page of repositories", "page", pageNum)
+			resultChan <- pageResult{page: pageNum, repos: repos}
+		}(page)
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	pageMap := make(map[int][]repository)
+	for result := range resultChan {
+		if result.err != nil {
+			if errors.Is(result.err, context.Canceled) || errors.Is(result.err, context.DeadlineExceeded) {
+				return nil, result.err
+			}
+			return nil, fmt.Errorf("error fetching page %d: %w", result.page, result.err)
+		}
+		pageMap[result.page] = result.repos
+	}
+
+	// Ensure pages are added in order
+	allRepos := firstPageRepos
+	for page := 2; page <= totalPages; page++ {
+		allRepos = append(allRepos, pageMap[page]...)
+	}
+
+	slog.Info("Successfully fetched all pages of repositories",
+		"pages", totalPages,
+		"elapsed_time", time.Since(startTime))
+
+	return allRepos, nil
+}
+
+// fetchSequentially fetches pages one at a time until a page shorter than the
+// first one is returned. It is used for servers that do not report the total
+// repository count.
+func (l *OrgLister) fetchSequentially(ctx context.Context, orgName string, firstPageRepos []repository) ([]repository, error) {
+	perPage := len(firstPageRepos)
+	allRepos := firstPageRepos
+	for page, repos := 2, firstPageRepos; perPage > 0 && len(repos) == perPage; page++ {
+		var err error
+		repos, _, err = l.fetchPage(ctx, orgName, page)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching page %d: %w", page, err)
+		}
+		allRepos = append(allRepos, repos...)
+	}
+	return allRepos, nil
+}
+
+// fetchPage fetches a single page of an organization's repositories and
+// returns it with the total repository count reported by the server, or -1
+// when the server did not report one
+func (l *OrgLister) fetchPage(ctx context.Context, orgName string, page int) ([]repository, int, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(pageSize))
+	endpoint := fmt.Sprintf("%s/orgs/%s/repos?%s", l.baseURL, url.PathEscape(orgName), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if l.config.Token != "" {
+		req.Header.Set("Authorization", "token "+l.config.Token)
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var repos []repository
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, 0, fmt.Errorf("error decoding repositories: %w", err)
+	}
+
+	totalCount, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		totalCount = -1
+	}
+
+	return repos, totalCount, nil
+}
+
+// toRepository converts a Gitea repository to the provider-neutral model
+func (l *OrgLister) toRepository(repo repository, orgName string) *provider.Repository {
+	owner := repo.Owner.Login
+	if owner == "" {
+		owner = orgName
+	}
+
+	return &provider.Repository{
+		Owner:         owner,
+		Name:          repo.Name,
+		CloneURL:      provider.ReplaceHost(repo.CloneURL, l.config.GitHost),
+		SSHURL:        repo.SSHURL,
+		DefaultBranch: repo.DefaultBranch,
+		Private:       repo.Private,
+		Archived:      repo.Archived,
+		Fork:          repo.Fork,
+		Visibility:    visibility(repo),
+		Language:      repo.Language,
+		Size:          repo.Size,
+	}
+}
+
+// visibility returns the visibility of a Gitea repository
+func visibility(repo repository) string {
+	switch {
+	case repo.Internal:
+		return "internal"
+	case repo.Private:
+		return "private"
+	default:
+		return "public"
+	}
+}
@@ -0,0 +1,189 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+)
+
+// fakeGitea is an httptest fake of the Gitea organization repository API
+type fakeGitea struct {
+	repos       []repository
+	reportTotal bool
+	// maxItems caps the page size, as MAX_RESPONSE_ITEMS does
+	maxItems int
+	requests atomic.Int32
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+
+	if r.URL.Path != "/api/v1/orgs/tools/repos" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "token test-token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if f.maxItems > 0 && limit > f.maxItems {
+		limit = f.maxItems
+	}
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(f.repos) {
+		start = len(f.repos)
+	}
+	if end > len(f.repos) {
+		end = len(f.repos)
+	}
+
+	if f.reportTotal {
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(f.repos)))
+	}
+	json.NewEncoder(w).Encode(f.repos[start:end])
+}
+
+// newFakeGitea creates a fake Gitea server with count repositories
+func newFakeGitea(t *testing.T, count int, reportTotal bool) (*fakeGitea, *httptest.Server) {
+	t.Helper()
+	return newCappedFakeGitea(t, count, reportTotal, 0)
+}
+
+// newCappedFakeGitea creates a fake Gitea server with count repositories that
+// returns at most maxItems repositories per page
+func newCappedFakeGitea(t *testing.T, count int, reportTotal bool, maxItems int) (*fakeGitea, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeGitea{reportTotal: reportTotal, maxItems: maxItems}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("repo-%03d", i)
+		repo := repository{
+			Name:          name,
+			CloneURL:      "https://forgejo.example.com/tools/" + name + ".git",
+			SSHURL:        "git@forgejo.example.com:tools/" + name + ".git",
+			DefaultBranch: "main",
+			Archived:      i%10 == 0,
+		}
+		repo.Owner.Login = "tools"
+		fake.repos = append(fake.repos, repo)
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func TestOrgLister_ListRepositories(t *testing.T) {
+	tests := []struct {
+		name         string
+		count        int
+		reportTotal  bool
+		maxItems     int
+		wantRequests int32
+	}{
+		{
+			name:         "single page",
+			count:        10,
+			reportTotal:  true,
+			wantRequests: 1,
+		},
+		{
+			name:         "concurrent pages with total count",
+			count:        120,
+			reportTotal:  true,
+			wantRequests: 3,
+		},
+		{
+			name:         "sequential pages without total count",
+			count:        100,
+			reportTotal:  false,
+			wantRequests: 3,
+		},
+		{
+			name:         "concurrent pages capped by the server",
+			count:        75,
+			reportTotal:  true,
+			maxItems:     20,
+			wantRequests: 4,
+		},
+		{
+			name:         "sequential pages capped by the server",
+			count:        60,
+			reportTotal:  false,
+			maxItems:     20,
+			wantRequests: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := newCappedFakeGitea(t, tt.count, tt.reportTotal, tt.maxItems)
+			cfg := &config.Config{
+				Token:    "test-token",
+				APIURL:   server.URL + "/api/v1/",
+				OrgNames: []string{"tools"},
+				Workers:  2,
+			}
+			lister := NewOrgLister(server.Client(), cfg)
+
+			repos, err := lister.ListRepositories(context.Background())
+			require.NoError(t, err)
+			require.Len(t, repos, tt.count)
+			assert.Equal(t, tt.wantRequests, fake.requests.Load())
+
+			// Pages are returned in order
+			for i, repo := range repos {
+				assert.Equal(t, fmt.Sprintf("tools/repo-%03d", i), repo.FullName())
+			}
+			assert.Equal(t, "https://forgejo.example.com/tools/repo-000.git", repos[0].CloneURL)
+			assert.True(t, repos[0].Archived)
+			assert.Equal(t, "main", repos[0].DefaultBranch)
+		})
+	}
+}
+
+func TestOrgLister_ListRepositories_Error(t *testing.T) {
+	_, server := newFakeGitea(t, 1, true)
+	cfg := &config.Config{
+		Token:    "wrong-token",
+		APIURL:   server.URL + "/api/v1",
+		OrgNames: []string{"tools"},
+		Workers:  2,
+	}
+	lister := NewOrgLister(server.Client(), cfg)
+
+	_, err := lister.ListRepositories(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status 401")
+}
+
+func TestOrgLister_ListRepositories_ContextCancellation(t *testing.T) {
+	_, server := newFakeGitea(t, 200, true)
+	cfg := &config.Config{
+		Token:    "test-token",
+		APIURL:   server.URL + "/api/v1",
+		OrgNames: []string{"tools"},
+		Workers:  2,
+	}
+	lister := NewOrgLister(server.Client(), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repos, err := lister.ListRepositories(ctx)
+	require.Error(t, err)
+	assert.Nil(t, repos)
+}
@@ -0,0 +1,237 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// repositoryFields selects the repository metadata used by ghloner. The
+// default branch head lets updates skip repositories that did not change.
+const repositoryFields = `
+	pageInfo { hasNextPage endCursor }
+	nodes {
+		name
+		owner { login }
+		url
+		sshUrl
+		isPrivate
+		isArchived
+		isFork
+		isEmpty
+		visibility
+		diskUsage
+		pushedAt
+		primaryLanguage { name }
+		repositoryTopics(first: 100) { nodes { topic { name } } }
+		defaultBranchRef { name target { oid } }
+	}`
+
+// Queries for the repositories of each kind of owner
+var (
+	organizationQuery = `query($login: String!, $cursor: String) {
+	owner: organization(login: $login) {
+		repositories(first: 100, after: $cursor, orderBy: {field: NAME, direction: ASC}) {` + repositoryFields + `
+		}
+	}
+}`
+	userQuery = `query($login: String!, $cursor: String) {
+	owner: user(login: $login) {
+		repositories(first: 100, after: $cursor, ownerAffiliations: [OWNER], orderBy: {field: NAME, direction: ASC}) {` + repositoryFields + `
+		}
+	}
+}`
+	viewerQuery = `query($cursor: String) {
+	owner: viewer {
+		repositories(first: 100, after: $cursor, affiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER], ownerAffiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER], orderBy: {field: NAME, direction: ASC}) {` + repositoryFields + `
+		}
+	}
+}`
+)
+
+// graphQLRequest is the body of a GraphQL API request
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// graphQLResponse is the body of a repository listing response
+type graphQLResponse struct {
+	Data struct {
+		Owner *struct {
+			Repositories struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []graphQLRepository `json:"nodes"`
+			} `json:"repositories"`
+		} `json:"owner"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQLRepository is the subset of the GraphQL repository object used by ghloner
+type graphQLRepository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	URL             string    `json:"url"`
+	SSHURL          string    `json:"sshUrl"`
+	IsPrivate       bool      `json:"isPrivate"`
+	IsArchived      bool      `json:"isArchived"`
+	IsFork          bool      `json:"isFork"`
+	IsEmpty         bool      `json:"isEmpty"`
+	Visibility      string    `json:"visibility"`
+	DiskUsage       int       `json:"diskUsage"`
+	PushedAt        time.Time `json:"pushedAt"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	DefaultBranchRef *struct {
+		Name   string `json:"name"`
+		Target struct {
+			OID string `json:"oid"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+// GraphQLLister lists repositories with the GraphQL API, which returns the
+// metadata of a repository, including its default branch head, in one query
+type GraphQLLister struct {
+	client *github.Client
+	config *config.Config
+}
+
+// NewGraphQLLister creates a new GraphQL repository lister
+func NewGraphQLLister(client *github.Client, cfg *config.Config) *GraphQLLister {
+	return &GraphQLLister{
+		client: client,
+		config: cfg,
+	}
+}
+
+// ListRepositories lists the repositories of an organization
+func (l *GraphQLLister) ListRepositories(ctx context.Context, orgName string) ([]*provider.Repository, error) {
+	return l.listAll(ctx, organizationQuery, orgName)
+}
+
+// ListUserRepositories lists the repositories owned by a user
+func (l *GraphQLLister) ListUserRepositories(ctx context.Context, userName string) ([]*provider.Repository, error) {
+	return l.listAll(ctx, userQuery, userName)
+}
+
+// ListAuthenticatedUserRepositories lists every repository the token can
+// access, including collaborator and organization member repositories
+func (l *GraphQLLister) ListAuthenticatedUserRepositories(ctx context.Context) ([]*provider.Repository, error) {
+	return l.listAll(ctx, viewerQuery, "")
+}
+
+// listAll follows the page cursor of a repository query. Pages depend on the
+// cursor of the previous page, so they are fetched sequentially.
+func (l *GraphQLLister) listAll(ctx context.Context, query, login string) ([]*provider.Repository, error) {
+	var allRepos []*provider.Repository
+	var cursor *string
+	for page := 1; ; page++ {
+		variables := map[string]interface{}{"cursor": cursor}
+		if login != "" {
+			variables["login"] = login
+		}
+
+		var resp graphQLResponse
+		if err := l.query(ctx, query, variables, &resp); err != nil {
+			return nil, fmt.Errorf("error fetching page %d: %w", page, err)
+		}
+		if resp.Data.Owner == nil {
+			return nil, fmt.Errorf("owner %s not found", login)
+		}
+
+		repos := resp.Data.Owner.Repositories
+		for _, node := range repos.Nodes {
+			allRepos = append(allRepos, l.toRepository(node))
+		}
+		slog.Debug("Fetched repository page", "page", page, "count", len(repos.Nodes))
+
+		if !repos.PageInfo.HasNextPage {
+			return allRepos, nil
+		}
+		endCursor := repos.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
+
+// query sends a GraphQL query and decodes the response into out
+func (l *GraphQLLister) query(ctx context.Context, query string, variables map[string]interface{}, out *graphQLResponse) error {
+	req, err := l.client.NewRequest("POST", graphQLURL(l.client), &graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.client.Do(ctx, req, out); err != nil {
+		return err
+	}
+
+	if len(out.Errors) > 0 {
+		messages := make([]string, 0, len(out.Errors))
+		for _, e := range out.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("graphql: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// graphQLURL returns the GraphQL endpoint of the API the client talks to. On
+// GitHub Enterprise Server it lives next to the REST API instead of below it.
+func graphQLURL(client *github.Client) string {
+	base := client.BaseURL.String()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
+
+// toRepository converts a GraphQL repository to the provider-neutral model
+func (l *GraphQLLister) toRepository(repo graphQLRepository) *provider.Repository {
+	result := &provider.Repository{
+		Owner:      repo.Owner.Login,
+		Name:       repo.Name,
+		CloneURL:   provider.ReplaceHost(repo.URL+".git", l.config.GitHost),
+		SSHURL:     repo.SSHURL,
+		Private:    repo.IsPrivate,
+		Archived:   repo.IsArchived,
+		Fork:       repo.IsFork,
+		Empty:      repo.IsEmpty,
+		Visibility: strings.ToLower(repo.Visibility),
+		Size:       repo.DiskUsage,
+		PushedAt:   repo.PushedAt,
+	}
+
+	if repo.PrimaryLanguage != nil {
+		result.Language = repo.PrimaryLanguage.Name
+	}
+	for _, node := range repo.RepositoryTopics.Nodes {
+		result.Topics = append(result.Topics, node.Topic.Name)
+	}
+	if repo.DefaultBranchRef != nil {
+		result.DefaultBranch = repo.DefaultBranchRef.Name
+		result.HeadOID = repo.DefaultBranchRef.Target.OID
+	}
+	return result
+}
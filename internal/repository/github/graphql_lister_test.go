@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// graphQLNode builds a repository node of a GraphQL response
+func graphQLNode(owner, name string, extra map[string]interface{}) map[string]interface{} {
+	node := map[string]interface{}{
+		"name":       name,
+		"owner":      map[string]interface{}{"login": owner},
+		"url":        fmt.Sprintf("https://github.com/%s/%s", owner, name),
+		"sshUrl":     fmt.Sprintf("git@github.com:%s/%s.git", owner, name),
+		"visibility": "PUBLIC",
+	}
+	for k, v := range extra {
+		node[k] = v
+	}
+	return node
+}
+
+// graphQLHandler serves repository pages keyed by the request cursor
+func graphQLHandler(t *testing.T, wantLogin string, pages map[string][]map[string]interface{}, order []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+
+		var req graphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if wantLogin != "" {
+			assert.Equal(t, wantLogin, req.Variables["login"])
+		}
+
+		cursor, _ := req.Variables["cursor"].(string)
+		next := ""
+		for i, c := range order {
+			if c == cursor && i+1 < len(order) {
+				next = order[i+1]
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"owner": map[string]interface{}{
+					"repositories": map[string]interface{}{
+						"pageInfo": map[string]interface{}{"hasNextPage": next != "", "endCursor": next},
+						"nodes":    pages[cursor],
+					},
+				},
+			},
+		})
+	})
+}
+
+func TestGraphQLLister_ListRepositories(t *testing.T) {
+	pushedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pages := map[string][]map[string]interface{}{
+		"": {
+			graphQLNode("testorg", "api", map[string]interface{}{
+				"visibility":       "INTERNAL",
+				"isPrivate":        true,
+				"diskUsage":        2048,
+				"pushedAt":         pushedAt.Format(time.RFC3339),
+				"primaryLanguage":  map[string]interface{}{"name": "Go"},
+				"repositoryTopics": map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"topic": map[string]interface{}{"name": "backend"}}}},
+				"defaultBranchRef": map[string]interface{}{"name": "main", "target": map[string]interface{}{"oid": "0123456789abcdef0123456789abcdef01234567"}},
+			}),
+		},
+		"cursor-2": {
+			graphQLNode("testorg", "empty", map[string]interface{}{"isEmpty": true, "pushedAt": nil, "defaultBranchRef": nil}),
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", graphQLHandler(t, "testorg", pages, []string{"", "cursor-2"}))
+
+	lister := NewGraphQLLister(newTestClient(t, mux), &config.Config{GitHost: "mirror.example.com"})
+	repos, err := lister.ListRepositories(context.Background(), "testorg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []*provider.Repository{
+		{
+			Owner:         "testorg",
+			Name:          "api",
+			CloneURL:      "https://mirror.example.com/testorg/api.git",
+			SSHURL:        "git@github.com:testorg/api.git",
+			DefaultBranch: "main",
+			Private:       true,
+			Visibility:    "internal",
+			Language:      "Go",
+			Topics:        []string{"backend"},
+			Size:          2048,
+			PushedAt:      pushedAt,
+			HeadOID:       "0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			Owner:      "testorg",
+			Name:       "empty",
+			CloneURL:   "https://mirror.example.com/testorg/empty.git",
+			SSHURL:     "git@github.com:testorg/empty.git",
+			Visibility: "public",
+			Empty:      true,
+		},
+	}, repos)
+}
+
+func TestGraphQLLister_Errors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"owner": null}, "errors": [{"message": "Could not resolve to an Organization with the login of 'missing'."}]}`))
+	})
+
+	lister := NewGraphQLLister(newTestClient(t, mux), &config.Config{})
+	_, err := lister.ListRepositories(context.Background(), "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not resolve to an Organization")
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{baseURL: "https://api.github.com/", want: "https://api.github.com/graphql"},
+		{baseURL: "https://ghes.example.com/api/v3/", want: "https://ghes.example.com/api/graphql"},
+	}
+
+	for _, tt := range tests {
+		client := github.NewClient(nil)
+		baseURL, err := url.Parse(tt.baseURL)
+		require.NoError(t, err)
+		client.BaseURL = baseURL
+
+		assert.Equal(t, tt.want, graphQLURL(client))
+	}
+}
+
+func TestSource_ListRepositories_GraphQL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", graphQLHandler(t, "someone", map[string][]map[string]interface{}{
+		"": {graphQLNode("someone", "dotfiles", nil)},
+	}, []string{""}))
+
+	source := NewSource(newTestClient(t, mux), &config.Config{UserName: "someone", GraphQL: true})
+	repos, err := source.ListRepositories(context.Background())
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "someone/dotfiles", repos[0].FullName())
+}
@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// propertyValue is a custom property value as returned by the API. Values are
+// decoded from raw JSON because multi-select properties are returned as arrays.
+type propertyValue struct {
+	PropertyName string          `json:"property_name"`
+	Value        json.RawMessage `json:"value"`
+}
+
+// repoPropertyValues holds the custom property values of a single repository
+type repoPropertyValues struct {
+	RepositoryName string          `json:"repository_name"`
+	Properties     []propertyValue `json:"properties"`
+}
+
+// LoadProperties attaches the organization custom property values to the
+// repositories of the configured organizations
+func (s *Source) LoadProperties(ctx context.Context, repos []*provider.Repository) error {
+	if len(s.config.OrgNames) == 0 {
+		return fmt.Errorf("custom properties are only available for organization sources")
+	}
+
+	// Logins are case-insensitive, so the configured organization may differ
+	// in case from the owner returned by the API
+	byOwner := make(map[string]map[string]*provider.Repository)
+	for _, repo := range repos {
+		owner := strings.ToLower(repo.Owner)
+		if byOwner[owner] == nil {
+			byOwner[owner] = make(map[string]*provider.Repository)
+		}
+		byOwner[owner][repo.Name] = repo
+	}
+
+	for _, owner := range s.config.OrgNames {
+		ownerRepos := byOwner[strings.ToLower(owner)]
+		values, err := s.listPropertyValues(ctx, owner)
+		if err != nil {
+			return fmt.Errorf("error fetching custom property values for %s: %w", owner, err)
+		}
+
+		for _, repoValues := range values {
+			repo, ok := ownerRepos[repoValues.RepositoryName]
+			if !ok {
+				continue
+			}
+			repo.Properties = make(map[string][]string)
+			for _, property := range repoValues.Properties {
+				if values := decodePropertyValue(property.Value); len(values) > 0 {
+					repo.Properties[property.PropertyName] = values
+				}
+			}
+		}
+
+		slog.Debug("Loaded custom property values", "organization", owner, "repositories", len(values))
+	}
+
+	return nil
+}
+
+// listPropertyValues fetches every page of custom property values for an organization
+func (s *Source) listPropertyValues(ctx context.Context, orgName string) ([]repoPropertyValues, error) {
+	var allValues []repoPropertyValues
+	for page := 1; page != 0; {
+		url := fmt.Sprintf("orgs/%s/properties/values?per_page=100&page=%d", orgName, page)
+		req, err := s.lister.client.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var values []repoPropertyValues
+		resp, err := s.lister.client.Do(ctx, req, &values)
+		if err != nil {
+			return nil, err
+		}
+
+		allValues = append(allValues, values...)
+		page = resp.NextPage
+	}
+	return allValues, nil
+}
+
+// decodePropertyValue decodes a single-value or multi-select property value
+func decodePropertyValue(raw json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		if single == "" {
+			return nil
+		}
+		return []string{single}
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err == nil {
+		return multiple
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+func TestSource_LoadProperties(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/testorg/properties/values", func(w http.ResponseWriter, r *http.Request) {
+		values := []map[string]interface{}{
+			{
+				"repository_name": "payments",
+				"properties": []map[string]interface{}{
+					{"property_name": "tier", "value": "critical"},
+					{"property_name": "team", "value": []string{"billing", "platform"}},
+					{"property_name": "data-classification", "value": nil},
+				},
+			},
+			{
+				"repository_name": "unknown-repo",
+				"properties": []map[string]interface{}{
+					{"property_name": "tier", "value": "low"},
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(values)
+	})
+
+	cfg := &config.Config{Workers: 2, OrgNames: []string{"testorg"}}
+	source := NewSource(newTestClient(t, mux), cfg)
+
+	repos := []*provider.Repository{
+		{Owner: "testorg", Name: "payments"},
+		{Owner: "testorg", Name: "docs"},
+	}
+
+	err := source.LoadProperties(context.Background(), repos)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"tier": {"critical"},
+		"team": {"billing", "platform"},
+	}, repos[0].Properties)
+	assert.Empty(t, repos[1].Properties)
+}
+
+func TestSource_LoadProperties_OrganizationCase(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/TestOrg/properties/values", func(w http.ResponseWriter, r *http.Request) {
+		values := []map[string]interface{}{
+			{
+				"repository_name": "payments",
+				"properties": []map[string]interface{}{
+					{"property_name": "tier", "value": "critical"},
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(values)
+	})
+
+	// The organization is configured in another case than the API login
+	cfg := &config.Config{Workers: 2, OrgNames: []string{"TestOrg"}}
+	source := NewSource(newTestClient(t, mux), cfg)
+
+	repos := []*provider.Repository{{Owner: "testorg", Name: "payments"}}
+	require.NoError(t, source.LoadProperties(context.Background(), repos))
+	assert.Equal(t, map[string][]string{"tier": {"critical"}}, repos[0].Properties)
+}
+
+func TestSource_LoadProperties_UserSource(t *testing.T) {
+	cfg := &config.Config{Workers: 2, UserName: "someone"}
+	source := NewSource(newTestClient(t, http.NewServeMux()), cfg)
+
+	err := source.LoadProperties(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only available for organization sources")
+}
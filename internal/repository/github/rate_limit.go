@@ -0,0 +1,24 @@
+package github
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LogRateLimit logs the remaining GitHub API budget. Querying the rate limit
+// does not count against it. Failures are logged and otherwise ignored.
+func (s *Source) LogRateLimit(ctx context.Context, stage string) {
+	limits, _, err := s.lister.client.RateLimit.Get(ctx)
+	if err != nil {
+		slog.Warn("Failed to get GitHub API rate limit", "stage", stage, "error", err)
+		return
+	}
+
+	core := limits.GetCore()
+	slog.Info("GitHub API rate limit",
+		"stage", stage,
+		"remaining", core.Remaining,
+		"limit", core.Limit,
+		"reset", core.Reset.Time.Format(time.RFC3339))
+}
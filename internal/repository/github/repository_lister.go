@@ -26,12 +26,108 @@ func NewRepositoryLister(client *github.Client, cfg *config.Config) *RepositoryL
 	}
 }
 
-// ListRepositories fetches all repositories for an organization
+// pageFetcher fetches a single page of repositories from one listing endpoint
+type pageFetcher func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error)
+
+// ListRepositories fetches all repositories of the configured type for an organization
 func (l *RepositoryLister) ListRepositories(ctx context.Context, orgName string) ([]*github.Repository, error) {
+	repoType := l.repoType()
+	if repoType == config.RepoTypeInternal {
+		repoType = "all"
+	}
+
+	repos, err := l.listAll(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return l.client.Repositories.ListByOrg(ctx, orgName, &github.RepositoryListByOrgOptions{
+			Type:        repoType,
+			ListOptions: opts,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if l.repoType() != config.RepoTypeInternal {
+		return repos, nil
+	}
+
+	var internalRepos []*github.Repository
+	for _, repo := range repos {
+		if repo.GetVisibility() == "internal" {
+			internalRepos = append(internalRepos, repo)
+		}
+	}
+	return internalRepos, nil
+}
+
+// ListUserRepositories fetches all repositories of the configured type for a user account
+func (l *RepositoryLister) ListUserRepositories(ctx context.Context, userName string) ([]*github.Repository, error) {
+	return l.listAll(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return l.client.Repositories.ListByUser(ctx, userName, &github.RepositoryListByUserOptions{
+			Type:        l.repoType(),
+			ListOptions: opts,
+		})
+	})
+}
+
+// ListAuthenticatedUserRepositories fetches every repository the token can access,
+// including repositories the user collaborates on or can see through an organization.
+// The API does not allow combining affiliation and type, so a type other than
+// all replaces the affiliation.
+func (l *RepositoryLister) ListAuthenticatedUserRepositories(ctx context.Context) ([]*github.Repository, error) {
+	opt := &github.RepositoryListByAuthenticatedUserOptions{
+		Affiliation: "owner,collaborator,organization_member",
+	}
+	if repoType := l.repoType(); repoType != "all" {
+		opt.Affiliation = ""
+		opt.Type = repoType
+	}
+
+	return l.listAll(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		pageOpt := *opt
+		pageOpt.ListOptions = opts
+		return l.client.Repositories.ListByAuthenticatedUser(ctx, &pageOpt)
+	})
+}
+
+// ListTeamRepositories fetches all repositories a team of an organization has
+// access to. When a permission is given, only repositories on which the team
+// has at least that permission are returned.
+func (l *RepositoryLister) ListTeamRepositories(ctx context.Context, orgName, teamSlug, permission string) ([]*github.Repository, error) {
+	repos, err := l.listAll(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return l.client.Teams.ListTeamReposBySlug(ctx, orgName, teamSlug, &opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if permission == "" {
+		return repos, nil
+	}
+
+	// The permissions map includes every level implied by the team's role
+	var permitted []*github.Repository
+	for _, repo := range repos {
+		if repo.GetPermissions()[permission] {
+			permitted = append(permitted, repo)
+		}
+	}
+	return permitted, nil
+}
+
+// repoType returns the configured repository type, defaulting to all
+func (l *RepositoryLister) repoType() string {
+	if l.config.RepoType == "" {
+		return "all"
+	}
+	return l.config.RepoType
+}
+
+// listAll fetches every page of a listing endpoint
+func (l *RepositoryLister) listAll(ctx context.Context, fetch pageFetcher) ([]*github.Repository, error) {
 	startTime := time.Now()
 
 	// Fetch first page
-	firstPageRepos, resp, err := l.fetchFirstPage(ctx, orgName)
+	firstPageRepos, resp, err := l.fetchFirstPage(ctx, fetch)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +137,7 @@ func (l *RepositoryLister) ListRepositories(ctx context.Context, orgName string)
 	}
 
 	// Fetch remaining pages concurrently
-	allRepos, err := l.fetchRemainingPages(ctx, orgName, firstPageRepos, resp)
+	allRepos, err := l.fetchRemainingPages(ctx, fetch, firstPageRepos, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -56,12 +152,8 @@ func (l *RepositoryLister) ListRepositories(ctx context.Context, orgName string)
 }
 
 // fetchFirstPage fetches the first page of repositories
-func (l *RepositoryLister) fetchFirstPage(ctx context.Context, orgName string) ([]*github.Repository, *github.Response, error) {
-	opt := &github.RepositoryListByOrgOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
-	}
-
-	repos, resp, err := l.client.Repositories.ListByOrg(ctx, orgName, opt)
+func (l *RepositoryLister) fetchFirstPage(ctx context.Context, fetch pageFetcher) ([]*github.Repository, *github.Response, error) {
+	repos, resp, err := fetch(ctx, github.ListOptions{PerPage: 100})
 	if err != nil {
 		return nil, nil, fmt.Errorf("error fetching first page of repositories: %w", err)
 	}
@@ -72,7 +164,7 @@ func (l *RepositoryLister) fetchFirstPage(ctx context.Context, orgName string) (
 // fetchRemainingPages fetches the remaining pages concurrently
 func (l *RepositoryLister) fetchRemainingPages(
 	ctx context.Context, 
-	orgName string, 
+	fetch pageFetcher, 
 	firstPageRepos []*github.Repository, 
 	resp *github.Response,
 ) ([]*github.Repository, error) {
@@ -118,11 +210,7 @@ func (l *RepositoryLister) fetchRemainingPages(
 				// Continue with the fetch
 			}
 
-			pageOpt := &github.RepositoryListByOrgOptions{
-				ListOptions: github.ListOptions{Page: pageNum, PerPage: 100},
-			}
-
-			repos, _, err := l.client.Repositories.ListByOrg(ctx, orgName, pageOpt)
+			repos, _, err := fetch(ctx, github.ListOptions{Page: pageNum, PerPage: 100})
 			if err != nil {
 				resultChan <- pageResult{page: pageNum, err: err}
 				return
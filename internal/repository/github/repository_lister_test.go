@@ -2,7 +2,11 @@ package github
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/google/go-github/v60/github"
@@ -59,9 +63,209 @@ func TestFetchRemainingPages_ContextCancellation(t *testing.T) {
 	cancel()
 
 	// Execute
-	allRepos, err := lister.fetchRemainingPages(ctx, orgName, firstPageRepos, resp)
+	fetch := func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return client.Repositories.ListByOrg(ctx, orgName, &github.RepositoryListByOrgOptions{ListOptions: opts})
+	}
+	allRepos, err := lister.fetchRemainingPages(ctx, fetch, firstPageRepos, resp)
 
 	// Should get context cancelled error
 	require.Error(t, err)
 	assert.Nil(t, allRepos)
-}
\ No newline at end of file
+}
+
+// newTestClient creates a GitHub client that talks to a local test server
+func newTestClient(t *testing.T, handler http.Handler) *github.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	return client
+}
+
+// writeRepositoryPage writes a page of repositories with pagination links
+func writeRepositoryPage(w http.ResponseWriter, r *http.Request, repos []*github.Repository, page, lastPage int) {
+	if page < lastPage {
+		next := *r.URL
+		query := next.Query()
+		query.Set("page", fmt.Sprint(page+1))
+		next.RawQuery = query.Encode()
+		last := *r.URL
+		query.Set("page", fmt.Sprint(lastPage))
+		last.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next", <http://%s%s>; rel="last"`, r.Host, next.String(), r.Host, last.String()))
+	}
+	json.NewEncoder(w).Encode(repos)
+}
+
+func TestListUserRepositories(t *testing.T) {
+	repos := fixtures.CreateTestRepositories(4)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/someone/repos", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			writeRepositoryPage(w, r, repos[:2], 1, 2)
+		case "2":
+			writeRepositoryPage(w, r, repos[2:], 2, 2)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	lister := NewRepositoryLister(newTestClient(t, mux), &config.Config{Workers: 2})
+
+	allRepos, err := lister.ListUserRepositories(context.Background(), "someone")
+	require.NoError(t, err)
+	require.Len(t, allRepos, 4)
+	for i, repo := range allRepos {
+		assert.Equal(t, repos[i].GetName(), repo.GetName())
+	}
+}
+
+func TestListAuthenticatedUserRepositories(t *testing.T) {
+	repos := fixtures.CreateTestRepositories(2)
+
+	var affiliation string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		affiliation = r.URL.Query().Get("affiliation")
+		writeRepositoryPage(w, r, repos, 1, 1)
+	})
+
+	lister := NewRepositoryLister(newTestClient(t, mux), &config.Config{Workers: 2})
+
+	allRepos, err := lister.ListAuthenticatedUserRepositories(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, allRepos, 2)
+	assert.Equal(t, "owner,collaborator,organization_member", affiliation)
+}
+
+func TestListRepositories_RepositoryType(t *testing.T) {
+	tests := []struct {
+		name      string
+		repoType  string
+		wantQuery string
+		wantRepos []string
+	}{
+		{
+			name:      "default type",
+			repoType:  "",
+			wantQuery: "all",
+			wantRepos: []string{"public-repo", "internal-repo"},
+		},
+		{
+			name:      "sources only",
+			repoType:  "sources",
+			wantQuery: "sources",
+			wantRepos: []string{"public-repo", "internal-repo"},
+		},
+		{
+			name:      "internal repositories by visibility",
+			repoType:  config.RepoTypeInternal,
+			wantQuery: "all",
+			wantRepos: []string{"internal-repo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotType string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/orgs/testorg/repos", func(w http.ResponseWriter, r *http.Request) {
+				gotType = r.URL.Query().Get("type")
+				writeRepositoryPage(w, r, []*github.Repository{
+					{Name: github.String("public-repo"), Visibility: github.String("public")},
+					{Name: github.String("internal-repo"), Visibility: github.String("internal")},
+				}, 1, 1)
+			})
+
+			lister := NewRepositoryLister(newTestClient(t, mux), &config.Config{Workers: 2, RepoType: tt.repoType})
+
+			repos, err := lister.ListRepositories(context.Background(), "testorg")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantQuery, gotType)
+
+			var names []string
+			for _, repo := range repos {
+				names = append(names, repo.GetName())
+			}
+			assert.Equal(t, tt.wantRepos, names)
+		})
+	}
+}
+
+func TestListAuthenticatedUserRepositories_RepositoryType(t *testing.T) {
+	var query url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		writeRepositoryPage(w, r, nil, 1, 1)
+	})
+
+	lister := NewRepositoryLister(newTestClient(t, mux), &config.Config{Workers: 2, RepoType: "owner"})
+
+	_, err := lister.ListAuthenticatedUserRepositories(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "owner", query.Get("type"))
+	assert.Empty(t, query.Get("affiliation"))
+}
+
+func TestListTeamRepositories(t *testing.T) {
+	repos := []*github.Repository{
+		{Name: github.String("admin-repo"), Permissions: map[string]bool{"admin": true, "maintain": true, "push": true, "pull": true}},
+		{Name: github.String("push-repo"), Permissions: map[string]bool{"push": true, "pull": true}},
+		{Name: github.String("read-repo"), Permissions: map[string]bool{"pull": true}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/testorg/teams/backend/repos", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			writeRepositoryPage(w, r, repos[:2], 1, 2)
+		default:
+			writeRepositoryPage(w, r, repos[2:], 2, 2)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		permission string
+		wantRepos  []string
+	}{
+		{
+			name:      "all permissions",
+			wantRepos: []string{"admin-repo", "push-repo", "read-repo"},
+		},
+		{
+			name:       "push or higher",
+			permission: "push",
+			wantRepos:  []string{"admin-repo", "push-repo"},
+		},
+		{
+			name:       "admin only",
+			permission: "admin",
+			wantRepos:  []string{"admin-repo"},
+		},
+	}
+
+	lister := NewRepositoryLister(newTestClient(t, mux), &config.Config{Workers: 2})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teamRepos, err := lister.ListTeamRepositories(context.Background(), "testorg", "backend", tt.permission)
+			require.NoError(t, err)
+
+			var names []string
+			for _, repo := range teamRepos {
+				names = append(names, repo.GetName())
+			}
+			assert.Equal(t, tt.wantRepos, names)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// Source lists the repositories of the configured GitHub organizations, user
+// or authenticated user
+type Source struct {
+	lister  *RepositoryLister
+	graphQL *GraphQLLister
+	config  *config.Config
+}
+
+// NewSource creates a new GitHub repository source
+func NewSource(client *github.Client, cfg *config.Config) *Source {
+	return &Source{
+		lister:  NewRepositoryLister(client, cfg),
+		graphQL: NewGraphQLLister(client, cfg),
+		config:  cfg,
+	}
+}
+
+// ListRepositories lists repositories from the configured GitHub owners
+func (s *Source) ListRepositories(ctx context.Context) ([]*provider.Repository, error) {
+	if s.config.GraphQL {
+		return s.listGraphQL(ctx)
+	}
+
+	switch {
+	case s.config.AuthenticatedUser:
+		repos, err := s.lister.ListAuthenticatedUserRepositories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return s.toRepositories(repos, ""), nil
+	case s.config.UserName != "":
+		repos, err := s.lister.ListUserRepositories(ctx, s.config.UserName)
+		if err != nil {
+			return nil, err
+		}
+		return s.toRepositories(repos, s.config.UserName), nil
+	}
+
+	var allRepos []*provider.Repository
+	for _, orgName := range s.config.OrgNames {
+		if len(s.config.Teams) > 0 {
+			repos, err := s.listTeams(ctx, orgName)
+			if err != nil {
+				return nil, err
+			}
+			allRepos = append(allRepos, s.toRepositories(repos, orgName)...)
+			continue
+		}
+
+		repos, err := s.lister.ListRepositories(ctx, orgName)
+		if err != nil {
+			return nil, fmt.Errorf("error listing repositories for %s: %w", orgName, err)
+		}
+		slog.Info("Found organization repositories", "count", len(repos), "organization", orgName)
+		allRepos = append(allRepos, s.toRepositories(repos, orgName)...)
+	}
+	return allRepos, nil
+}
+
+// listGraphQL lists repositories from the configured GitHub owners with the GraphQL API
+func (s *Source) listGraphQL(ctx context.Context) ([]*provider.Repository, error) {
+	switch {
+	case s.config.AuthenticatedUser:
+		return s.graphQL.ListAuthenticatedUserRepositories(ctx)
+	case s.config.UserName != "":
+		return s.graphQL.ListUserRepositories(ctx, s.config.UserName)
+	}
+
+	var allRepos []*provider.Repository
+	for _, orgName := range s.config.OrgNames {
+		repos, err := s.graphQL.ListRepositories(ctx, orgName)
+		if err != nil {
+			return nil, fmt.Errorf("error listing repositories for %s: %w", orgName, err)
+		}
+		slog.Info("Found organization repositories", "count", len(repos), "organization", orgName)
+		allRepos = append(allRepos, repos...)
+	}
+	return allRepos, nil
+}
+
+// listTeams lists the repositories of every configured team of an organization.
+// Repositories shared by several teams are returned once.
+func (s *Source) listTeams(ctx context.Context, orgName string) ([]*github.Repository, error) {
+	seen := make(map[string]bool)
+	var allRepos []*github.Repository
+	for _, teamSlug := range s.config.Teams {
+		repos, err := s.lister.ListTeamRepositories(ctx, orgName, teamSlug, s.config.TeamPermission)
+		if err != nil {
+			return nil, fmt.Errorf("error listing repositories for team %s/%s: %w", orgName, teamSlug, err)
+		}
+		slog.Info("Found team repositories", "count", len(repos), "organization", orgName, "team", teamSlug)
+
+		for _, repo := range repos {
+			if seen[repo.GetName()] {
+				continue
+			}
+			seen[repo.GetName()] = true
+			allRepos = append(allRepos, repo)
+		}
+	}
+	return allRepos, nil
+}
+
+// toRepositories converts GitHub repositories to the provider-neutral model.
+// The owner name is used for repositories whose owner is not part of the API response.
+func (s *Source) toRepositories(repos []*github.Repository, ownerName string) []*provider.Repository {
+	result := make([]*provider.Repository, 0, len(repos))
+	for _, repo := range repos {
+		result = append(result, toRepository(repo, ownerName, s.config.GitHost))
+	}
+	return result
+}
+
+// toRepository converts a single GitHub repository to the provider-neutral model
+func toRepository(repo *github.Repository, ownerName, gitHost string) *provider.Repository {
+	owner := repo.GetOwner().GetLogin()
+	if owner == "" {
+		owner = ownerName
+	}
+
+	return &provider.Repository{
+		Owner:         owner,
+		Name:          repo.GetName(),
+		CloneURL:      CloneURL(repo, owner, gitHost),
+		SSHURL:        repo.GetSSHURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Private:       repo.GetPrivate(),
+		Archived:      repo.GetArchived(),
+		Fork:          repo.GetFork(),
+		Visibility:    visibility(repo),
+		Language:      repo.GetLanguage(),
+		Topics:        repo.Topics,
+		Size:          repo.GetSize(),
+		PushedAt:      repo.GetPushedAt().Time,
+	}
+}
+
+// visibility returns the visibility of a repository, deriving it from the
+// private flag when the API response does not include it
+func visibility(repo *github.Repository) string {
+	if v := repo.GetVisibility(); v != "" {
+		return v
+	}
+	if repo.GetPrivate() {
+		return "private"
+	}
+	return "public"
+}
@@ -0,0 +1,118 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+)
+
+func TestSource_ListRepositories_Organizations(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/orga/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeRepositoryPage(w, r, []*github.Repository{
+			{
+				Name:     github.String("shared"),
+				Owner:    &github.User{Login: github.String("orga")},
+				CloneURL: github.String("https://github.com/orga/shared.git"),
+			},
+		}, 1, 1)
+	})
+	mux.HandleFunc("/orgs/orgb/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeRepositoryPage(w, r, []*github.Repository{
+			{
+				Name:          github.String("shared"),
+				CloneURL:      github.String("https://github.com/orgb/shared.git"),
+				DefaultBranch: github.String("main"),
+				Archived:      github.Bool(true),
+			},
+		}, 1, 1)
+	})
+
+	cfg := &config.Config{Workers: 2, OrgNames: []string{"orga", "orgb"}}
+	source := NewSource(newTestClient(t, mux), cfg)
+
+	repos, err := source.ListRepositories(context.Background())
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+
+	assert.Equal(t, "orga/shared", repos[0].FullName())
+	assert.Equal(t, "https://github.com/orga/shared.git", repos[0].CloneURL)
+
+	// Visibility is derived from the private flag when the API omits it
+	assert.Equal(t, "public", repos[0].Visibility)
+
+	// Owner falls back to the organization being listed
+	assert.Equal(t, "orgb/shared", repos[1].FullName())
+	assert.Equal(t, "main", repos[1].DefaultBranch)
+	assert.True(t, repos[1].Archived)
+}
+
+func TestSource_ListRepositories_User(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/someone/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeRepositoryPage(w, r, []*github.Repository{
+			{Name: github.String("dotfiles")},
+		}, 1, 1)
+	})
+
+	cfg := &config.Config{Workers: 2, UserName: "someone", GitHost: "git.example.com"}
+	source := NewSource(newTestClient(t, mux), cfg)
+
+	repos, err := source.ListRepositories(context.Background())
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "someone", repos[0].Owner)
+	assert.Equal(t, "https://git.example.com/someone/dotfiles.git", repos[0].CloneURL)
+}
+
+func TestSource_ListRepositories_Teams(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/testorg/teams/backend/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeRepositoryPage(w, r, []*github.Repository{
+			{Name: github.String("api")},
+			{Name: github.String("shared-lib")},
+		}, 1, 1)
+	})
+	mux.HandleFunc("/orgs/testorg/teams/frontend/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeRepositoryPage(w, r, []*github.Repository{
+			{Name: github.String("web")},
+			{Name: github.String("shared-lib")},
+		}, 1, 1)
+	})
+	mux.HandleFunc("/orgs/testorg/repos", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("organization listing should not be used for team sources")
+	})
+
+	cfg := &config.Config{Workers: 2, OrgNames: []string{"testorg"}, Teams: []string{"backend", "frontend"}}
+	source := NewSource(newTestClient(t, mux), cfg)
+
+	repos, err := source.ListRepositories(context.Background())
+	require.NoError(t, err)
+
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.FullName())
+	}
+	assert.Equal(t, []string{"testorg/api", "testorg/shared-lib", "testorg/web"}, names)
+}
+
+func TestSource_LogRateLimit(t *testing.T) {
+	requested := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rate_limit", func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1700000000}}}`))
+	})
+
+	source := NewSource(newTestClient(t, mux), &config.Config{Workers: 1})
+	source.LogRateLimit(context.Background(), "start")
+	assert.True(t, requested)
+
+	// Errors are logged, not returned
+	NewSource(newTestClient(t, http.NotFoundHandler()), &config.Config{Workers: 1}).LogRateLimit(context.Background(), "end")
+}
@@ -0,0 +1,24 @@
+package github
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// CloneURL returns the HTTPS clone URL reported by the API for a repository.
+// When gitHost is set, the host of the URL is replaced with it. Repositories
+// without a clone URL fall back to a URL built from the owner and name.
+func CloneURL(repo *github.Repository, ownerName, gitHost string) string {
+	cloneURL := repo.GetCloneURL()
+	if cloneURL == "" {
+		host := gitHost
+		if host == "" {
+			host = "github.com"
+		}
+		return fmt.Sprintf("https://%s/%s/%s.git", host, ownerName, repo.GetName())
+	}
+
+	return provider.ReplaceHost(cloneURL, gitHost)
+}
@@ -0,0 +1,57 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     *github.Repository
+		owner    string
+		gitHost  string
+		expected string
+	}{
+		{
+			name: "clone url from api",
+			repo: &github.Repository{
+				Name:     github.String("repo"),
+				CloneURL: github.String("https://ghes.example.com/org/repo.git"),
+			},
+			owner:    "org",
+			expected: "https://ghes.example.com/org/repo.git",
+		},
+		{
+			name: "git host overrides api host",
+			repo: &github.Repository{
+				Name:     github.String("repo"),
+				CloneURL: github.String("https://ghes.internal/org/repo.git"),
+			},
+			owner:    "org",
+			gitHost:  "git.example.com",
+			expected: "https://git.example.com/org/repo.git",
+		},
+		{
+			name:     "fallback without clone url",
+			repo:     &github.Repository{Name: github.String("repo")},
+			owner:    "org",
+			expected: "https://github.com/org/repo.git",
+		},
+		{
+			name:     "fallback with git host",
+			repo:     &github.Repository{Name: github.String("repo")},
+			owner:    "org",
+			gitHost:  "ghes.example.com",
+			expected: "https://ghes.example.com/org/repo.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CloneURL(tt.repo, tt.owner, tt.gitHost))
+		})
+	}
+}
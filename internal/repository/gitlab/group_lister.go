@@ -0,0 +1,195 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// DefaultAPIURL is the API base URL of gitlab.com
+const DefaultAPIURL = "https://gitlab.com/api/v4"
+
+// project is the subset of the GitLab project API response used by ghloner
+type project struct {
+	Path          string    `json:"path"`
+	HTTPURLToRepo string    `json:"http_url_to_repo"`
+	SSHURLToRepo  string    `json:"ssh_url_to_repo"`
+	DefaultBranch string    `json:"default_branch"`
+	Visibility    string    `json:"visibility"`
+	Archived      bool      `json:"archived"`
+	Topics        []string  `json:"topics"`
+	LastActivity  time.Time `json:"last_activity_at"`
+	Namespace     struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	ForkedFromProject *struct {
+		ID int64 `json:"id"`
+	} `json:"forked_from_project"`
+}
+
+// group is the subset of the GitLab group API response used by ghloner
+type group struct {
+	FullPath string `json:"full_path"`
+}
+
+// GroupLister lists the projects of GitLab groups and all of their subgroups
+type GroupLister struct {
+	httpClient *http.Client
+	baseURL    string
+	config     *config.Config
+}
+
+// NewGroupLister creates a new GitLab group lister
+func NewGroupLister(httpClient *http.Client, cfg *config.Config) *GroupLister {
+	baseURL := cfg.APIURL
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+
+	return &GroupLister{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		config:     cfg,
+	}
+}
+
+// ListRepositories lists the projects of every configured group, recursing into subgroups
+func (l *GroupLister) ListRepositories(ctx context.Context) ([]*provider.Repository, error) {
+	var allRepos []*provider.Repository
+	for _, groupPath := range l.config.OrgNames {
+		startTime := time.Now()
+
+		repos, err := l.listGroup(ctx, groupPath)
+		if err != nil {
+			return nil, fmt.Errorf("error listing projects for group %s: %w", groupPath, err)
+		}
+
+		slog.Info("Found group projects",
+			"count", len(repos),
+			"group", groupPath,
+			"elapsed_time", time.Since(startTime))
+		allRepos = append(allRepos, repos...)
+	}
+	return allRepos, nil
+}
+
+// listGroup lists the projects of a group and, recursively, of its subgroups
+func (l *GroupLister) listGroup(ctx context.Context, groupPath string) ([]*provider.Repository, error) {
+	escaped := url.PathEscape(groupPath)
+
+	// Projects shared into the group belong to other namespaces, which are
+	// outside the group tree and may be shared into several synced groups
+	projects, err := getAll[project](ctx, l, "/groups/"+escaped+"/projects", url.Values{"with_shared": {"false"}})
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make([]*provider.Repository, 0, len(projects))
+	for _, p := range projects {
+		repos = append(repos, l.toRepository(p, groupPath))
+	}
+
+	subgroups, err := getAll[group](ctx, l, "/groups/"+escaped+"/subgroups", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subgroup := range subgroups {
+		slog.Debug("Listing subgroup", "group", subgroup.FullPath)
+		subRepos, err := l.listGroup(ctx, subgroup.FullPath)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, subRepos...)
+	}
+
+	return repos, nil
+}
+
+// getAll fetches every page of a list endpoint with the query parameters params
+func getAll[T any](ctx context.Context, l *GroupLister, endpoint string, params url.Values) ([]T, error) {
+	var items []T
+	for page := 1; page != 0; {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		var pageItems []T
+		nextPage, err := l.getPage(ctx, endpoint, params, page, &pageItems)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+		page = nextPage
+	}
+	return items, nil
+}
+
+// getPage fetches a single page of a list endpoint into out and returns the
+// next page number, or zero when there are no more pages
+func (l *GroupLister) getPage(ctx context.Context, endpoint string, params url.Values, page int, out interface{}) (int, error) {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("per_page", "100")
+	query.Set("page", strconv.Itoa(page))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	if l.config.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", l.config.Token)
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching %s page %d: %w", endpoint, page, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("error fetching %s page %d: unexpected status %s", endpoint, page, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("error decoding %s page %d: %w", endpoint, page, err)
+	}
+
+	nextPage, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return nextPage, nil
+}
+
+// toRepository converts a GitLab project to the provider-neutral model
+func (l *GroupLister) toRepository(p project, groupPath string) *provider.Repository {
+	owner := p.Namespace.FullPath
+	if owner == "" {
+		owner = groupPath
+	}
+
+	return &provider.Repository{
+		Owner:         owner,
+		Name:          p.Path,
+		CloneURL:      provider.ReplaceHost(p.HTTPURLToRepo, l.config.GitHost),
+		SSHURL:        p.SSHURLToRepo,
+		DefaultBranch: p.DefaultBranch,
+		Private:       p.Visibility != "public",
+		Archived:      p.Archived,
+		Fork:          p.ForkedFromProject != nil,
+		Visibility:    p.Visibility,
+		Topics:        p.Topics,
+		PushedAt:      p.LastActivity,
+	}
+}
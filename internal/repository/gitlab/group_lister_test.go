@@ -0,0 +1,142 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+)
+
+// newTestServer creates a fake GitLab API serving a group with a nested
+// subgroup, into which a project of another namespace is shared
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	projects := map[string][]map[string]interface{}{
+		"platform": {
+			{"path": "api", "namespace": map[string]string{"full_path": "platform"}, "http_url_to_repo": "https://gitlab.example.com/platform/api.git", "visibility": "private"},
+			{"path": "web", "namespace": map[string]string{"full_path": "platform"}, "http_url_to_repo": "https://gitlab.example.com/platform/web.git", "visibility": "public"},
+		},
+		"platform/tools": {
+			{"path": "cli", "namespace": map[string]string{"full_path": "platform/tools"}, "http_url_to_repo": "https://gitlab.example.com/platform/tools/cli.git", "forked_from_project": map[string]int{"id": 1}},
+		},
+		"platform/tools/legacy": {
+			{"path": "old", "namespace": map[string]string{"full_path": "platform/tools/legacy"}, "http_url_to_repo": "https://gitlab.example.com/platform/tools/legacy/old.git", "archived": true},
+		},
+	}
+	shared := map[string][]map[string]interface{}{
+		"platform/tools": {
+			{"path": "sdk", "namespace": map[string]string{"full_path": "partners"}, "http_url_to_repo": "https://gitlab.example.com/partners/sdk.git"},
+		},
+	}
+	subgroups := map[string][]map[string]string{
+		"platform":       {{"full_path": "platform/tools"}},
+		"platform/tools": {{"full_path": "platform/tools/legacy"}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/groups/{group}/{kind}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		group := r.PathValue("group")
+		switch r.PathValue("kind") {
+		case "projects":
+			items := projects[group]
+			if r.URL.Query().Get("with_shared") != "false" {
+				items = append(append([]map[string]interface{}{}, items...), shared[group]...)
+			}
+			// Serve one project per page to exercise pagination
+			page := 1
+			if p := r.URL.Query().Get("page"); p != "" && p != "1" {
+				page = 2
+			}
+			if len(items) > 1 && page == 1 {
+				w.Header().Set("X-Next-Page", "2")
+				items = items[:1]
+			} else if len(items) > 1 {
+				items = items[1:]
+			}
+			json.NewEncoder(w).Encode(items)
+		case "subgroups":
+			items := subgroups[group]
+			if items == nil {
+				items = []map[string]string{}
+			}
+			json.NewEncoder(w).Encode(items)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewGroupLister_DefaultAPIURL(t *testing.T) {
+	lister := NewGroupLister(http.DefaultClient, &config.Config{})
+	assert.Equal(t, DefaultAPIURL, lister.baseURL)
+}
+
+func TestGroupLister_ListRepositories(t *testing.T) {
+	server := newTestServer(t)
+	cfg := &config.Config{
+		Token:    "test-token",
+		APIURL:   server.URL + "/api/v4/",
+		OrgNames: []string{"platform"},
+	}
+	lister := NewGroupLister(server.Client(), cfg)
+
+	repos, err := lister.ListRepositories(context.Background())
+	require.NoError(t, err)
+
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.FullName())
+	}
+	sort.Strings(names)
+	// Projects shared into the groups are not listed
+	assert.Equal(t, []string{
+		"platform/api",
+		"platform/tools/cli",
+		"platform/tools/legacy/old",
+		"platform/web",
+	}, names)
+
+	for _, repo := range repos {
+		switch repo.FullName() {
+		case "platform/api":
+			assert.True(t, repo.Private)
+			assert.Equal(t, "https://gitlab.example.com/platform/api.git", repo.CloneURL)
+		case "platform/web":
+			assert.False(t, repo.Private)
+		case "platform/tools/cli":
+			assert.True(t, repo.Fork)
+		case "platform/tools/legacy/old":
+			assert.True(t, repo.Archived)
+		}
+	}
+}
+
+func TestGroupLister_ListRepositories_Unauthorized(t *testing.T) {
+	server := newTestServer(t)
+	cfg := &config.Config{
+		Token:    "wrong-token",
+		APIURL:   server.URL + "/api/v4",
+		OrgNames: []string{"platform"},
+	}
+	lister := NewGroupLister(server.Client(), cfg)
+
+	_, err := lister.ListRepositories(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status 401")
+}
@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// manifest is the layout of a manifest file
+type manifest struct {
+	Repositories []entry `json:"repositories" yaml:"repositories"`
+}
+
+// entry describes a single repository of a manifest
+type entry struct {
+	// URL is the clone URL, any remote supported by git including file://
+	URL string `json:"url" yaml:"url"`
+	// Path is the slash-separated target path relative to the output
+	// directory. It defaults to the repository name taken from the URL.
+	Path string `json:"path" yaml:"path"`
+	// Branch is the branch to check out, the remote HEAD when empty
+	Branch string `json:"branch" yaml:"branch"`
+}
+
+// Lister lists the repositories of a local manifest file
+type Lister struct {
+	config *config.Config
+}
+
+// NewLister creates a new manifest lister
+func NewLister(cfg *config.Config) *Lister {
+	return &Lister{
+		config: cfg,
+	}
+}
+
+// ListRepositories reads the manifest and returns its repositories
+func (l *Lister) ListRepositories(ctx context.Context) ([]*provider.Repository, error) {
+	data, err := os.ReadFile(l.config.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	var m manifest
+	if err := decode(l.config.Manifest, data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing manifest %s: %w", l.config.Manifest, err)
+	}
+
+	repos := make([]*provider.Repository, 0, len(m.Repositories))
+	seen := make(map[string]bool)
+	for i, e := range m.Repositories {
+		repo, err := toRepository(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest entry %d: %w", i+1, err)
+		}
+		if seen[repo.FullName()] {
+			return nil, fmt.Errorf("invalid manifest entry %d: duplicate path %s", i+1, repo.FullName())
+		}
+		seen[repo.FullName()] = true
+		repos = append(repos, repo)
+	}
+
+	// A clone inside another clone would be swept as a leftover directory
+	// of the outer one's worktree
+	for i, repo := range repos {
+		for dir := repo.Owner; dir != ""; dir = parentDir(dir) {
+			if seen[dir] {
+				return nil, fmt.Errorf("invalid manifest entry %d: path %s is inside the path %s of another entry", i+1, repo.FullName(), dir)
+			}
+		}
+	}
+
+	slog.Info("Read manifest", "path", l.config.Manifest, "repositories", len(repos))
+	return repos, nil
+}
+
+// decode parses JSON manifests by their extension and everything else as YAML
+func decode(name string, data []byte, m *manifest) error {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(m)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(m)
+}
+
+// parentDir returns the parent of a slash-separated path, or an empty string
+// for a single segment
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// toRepository converts a manifest entry. The directory part of the target
+// path becomes the owner, so nested paths map onto the owner layout.
+func toRepository(e entry) (*provider.Repository, error) {
+	if e.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	target := e.Path
+	if target == "" {
+		target = strings.TrimSuffix(path.Base(strings.TrimSuffix(e.URL, "/")), ".git")
+	}
+	target = path.Clean(target)
+	if path.IsAbs(target) || target == "." || target == ".." || strings.HasPrefix(target, "../") {
+		return nil, fmt.Errorf("path %q must be relative to the output directory", e.Path)
+	}
+	if first, _, _ := strings.Cut(target, "/"); first == config.StateDirName {
+		return nil, fmt.Errorf("path %q is inside the %s state directory", target, config.StateDirName)
+	}
+
+	owner := path.Dir(target)
+	if owner == "." {
+		owner = ""
+	}
+
+	return &provider.Repository{
+		Owner:    owner,
+		Name:     path.Base(target),
+		CloneURL: e.URL,
+		Branch:   e.Branch,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+func TestListRepositories(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		want        []*provider.Repository
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "yaml manifest",
+			file: "repos.yaml",
+			content: `repositories:
+  - url: file:///srv/git/tools.git
+  - url: https://example.com/team/api.git
+    path: services/backend/api
+    branch: release
+`,
+			want: []*provider.Repository{
+				{Name: "tools", CloneURL: "file:///srv/git/tools.git"},
+				{Owner: "services/backend", Name: "api", CloneURL: "https://example.com/team/api.git", Branch: "release"},
+			},
+		},
+		{
+			name:    "json manifest",
+			file:    "repos.json",
+			content: `{"repositories": [{"url": "git@example.com:team/web.git", "path": "frontend/web"}]}`,
+			want: []*provider.Repository{
+				{Owner: "frontend", Name: "web", CloneURL: "git@example.com:team/web.git"},
+			},
+		},
+		{
+			name:        "missing url",
+			file:        "repos.yaml",
+			content:     "repositories:\n  - path: tools\n",
+			wantErr:     true,
+			errContains: "invalid manifest entry 1: url is required",
+		},
+		{
+			name:        "path outside the output directory",
+			file:        "repos.yaml",
+			content:     "repositories:\n  - url: file:///srv/git/tools.git\n    path: ../tools\n",
+			wantErr:     true,
+			errContains: "must be relative to the output directory",
+		},
+		{
+			name:        "duplicate path",
+			file:        "repos.yaml",
+			content:     "repositories:\n  - url: file:///a/tools.git\n  - url: file:///b/tools.git\n",
+			wantErr:     true,
+			errContains: "duplicate path tools",
+		},
+		{
+			name:        "path inside another entry",
+			file:        "repos.yaml",
+			content:     "repositories:\n  - url: file:///a/plugin.git\n    path: tools/plugins/plugin\n  - url: file:///b/tools.git\n",
+			wantErr:     true,
+			errContains: "invalid manifest entry 1: path tools/plugins/plugin is inside the path tools of another entry",
+		},
+		{
+			name:        "path inside the state directory",
+			file:        "repos.yaml",
+			content:     "repositories:\n  - url: file:///srv/git/tools.git\n    path: .ghloner/tools\n",
+			wantErr:     true,
+			errContains: "is inside the .ghloner state directory",
+		},
+		{
+			name:        "unknown field",
+			file:        "repos.json",
+			content:     `{"repositories": [{"url": "file:///srv/git/tools.git", "ref": "main"}]}`,
+			wantErr:     true,
+			errContains: "error parsing manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifestPath := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(manifestPath, []byte(tt.content), 0644))
+
+			lister := NewLister(&config.Config{Manifest: manifestPath})
+			repos, err := lister.ListRepositories(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, repos)
+		})
+	}
+}
+
+func TestListRepositories_MissingFile(t *testing.T) {
+	lister := NewLister(&config.Config{Manifest: filepath.Join(t.TempDir(), "missing.yaml")})
+
+	_, err := lister.ListRepositories(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error reading manifest")
+}
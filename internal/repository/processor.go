@@ -2,68 +2,135 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
-	"github.com/google/go-github/v60/github"
 	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/httpcache"
 	"github.com/truemilk/ghloner/internal/repository/concurrency"
+	"github.com/truemilk/ghloner/internal/repository/filter"
 	"github.com/truemilk/ghloner/internal/repository/git"
-	repoGithub "github.com/truemilk/ghloner/internal/repository/github"
 	"github.com/truemilk/ghloner/internal/repository/progress"
+	"github.com/truemilk/ghloner/internal/repository/provider"
 	"github.com/truemilk/ghloner/internal/repository/storage"
+	"golang.org/x/oauth2"
 )
 
 // Processor coordinates the repository processing workflow
 type Processor struct {
 	config        *config.Config
-	client        *github.Client
-	repoLister    *repoGithub.RepositoryLister
+	source        provider.Source
 	gitManager    *git.Manager
 	fileManager   *storage.FileManager
 	workerPool    *concurrency.WorkerPool
+	httpCache     *httpcache.Cache
+	tokenSource   oauth2.TokenSource
 }
 
 // NewProcessor creates a new processor instance
-func NewProcessor(client *github.Client, cfg *config.Config) *Processor {
+func NewProcessor(source provider.Source, cfg *config.Config) *Processor {
 	return &Processor{
 		config:      cfg,
-		client:      client,
-		repoLister:  repoGithub.NewRepositoryLister(client, cfg),
+		source:      source,
 		gitManager:  git.NewManager(cfg),
 		fileManager: storage.NewFileManager(cfg),
 		workerPool:  concurrency.NewWorkerPool(cfg.Workers),
+		tokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.Token}),
 	}
 }
 
+// SetHTTPCache sets the API response cache whose hits are reported in the summary
+func (p *Processor) SetHTTPCache(cache *httpcache.Cache) {
+	p.httpCache = cache
+}
+
+// SetTokenSource sets the source of the token used for git operations. It
+// defaults to the configured token.
+func (p *Processor) SetTokenSource(ts oauth2.TokenSource) {
+	p.tokenSource = ts
+}
+
+// SetSSHAuth sets the authentication of SSH remotes, and clones repositories
+// from their SSH URL
+func (p *Processor) SetSSHAuth(auth *git.SSHAuth) {
+	p.gitManager.SetSSHAuth(auth)
+}
+
 // Run executes the repository processing workflow
 func (p *Processor) Run(ctx context.Context) error {
 	slog.Info("Starting processor", "workers", p.config.Workers, "retries", p.config.RetryCount)
 
-	// List repositories from GitHub
-	allRepos, err := p.repoLister.ListRepositories(ctx, p.config.OrgName)
+	repoFilter, err := filter.New(p.config)
+	if err != nil {
+		return err
+	}
+
+	// Remove clones an interrupted run left behind, so they are cloned again
+	if err := p.fileManager.CleanupIncompleteClones(); err != nil {
+		return err
+	}
+
+	// Log the API budget before and after the run
+	if reporter, ok := p.source.(provider.RateLimitReporter); ok {
+		reporter.LogRateLimit(ctx, "start")
+		defer reporter.LogRateLimit(context.WithoutCancel(ctx), "end")
+	}
+
+	// List repositories from the hosting provider
+	listedRepos, err := p.source.ListRepositories(ctx)
 	if err != nil {
 		return err
 	}
 	
-	slog.Info("Found repositories", "count", len(allRepos), "organization", p.config.OrgName)
+	slog.Info("Found repositories", "count", len(listedRepos), "source", p.config.SourceName())
+
+	// Load custom property values when filters use them
+	if repoFilter.NeedsProperties() {
+		loader, ok := p.source.(provider.PropertyLoader)
+		if !ok {
+			return fmt.Errorf("custom property filters are not supported by the %s provider", p.config.Provider)
+		}
+		if err := loader.LoadProperties(ctx, listedRepos); err != nil {
+			return err
+		}
+	}
+
+	// Apply repository filters
+	allRepos, filteredRepos := repoFilter.Apply(listedRepos)
+	if len(filteredRepos) > 0 {
+		slog.Info("Filtered out repositories", "count", len(filteredRepos), "remaining", len(allRepos))
+	}
 
 	// Save repository list
-	if err := p.fileManager.SaveRepositoryList(allRepos, p.config.OrgName); err != nil {
+	if err := p.fileManager.SaveRepositoryList(allRepos); err != nil {
 		return err
 	}
 
-	// Clean up old repositories
-	if err := p.fileManager.CleanupOldRepositories(allRepos); err != nil {
+	// Clean up old repositories, keeping clones of filtered out repositories unless pruning is requested
+	keepRepos := listedRepos
+	if p.config.PruneFiltered {
+		keepRepos = allRepos
+	}
+	if err := p.fileManager.CleanupOldRepositories(keepRepos); err != nil {
 		return err
 	}
 
 	// Create progress tracker
 	showProgress := !p.config.NoProgress
 	progressTracker := progress.NewProgressTracker(len(allRepos), p.config.Workers, showProgress, p.config.ProgressStyle)
+	progressTracker.SetFilteredRepos(len(filteredRepos))
+	if p.httpCache != nil {
+		progressTracker.SetCacheStats(p.httpCache.Stats())
+	}
 	p.workerPool.SetProgressTracker(progressTracker)
 
 	// Process repositories
-	if err := p.processRepositories(ctx, allRepos); err != nil {
+	err = p.processRepositories(ctx, allRepos)
+	progressTracker.SetScrubbedRemotes(p.gitManager.ScrubbedRemotes())
+	progressTracker.SetPreservedBranches(p.gitManager.PreservedBranches())
+	progressTracker.SetBranchSwitches(p.gitManager.BranchSwitches())
+	progressTracker.SetLocalChanges(p.gitManager.LocalChangeReports())
+	if err != nil {
 		progressTracker.PrintSummary()
 		return err
 	}
@@ -74,8 +141,13 @@ func (p *Processor) Run(ctx context.Context) error {
 }
 
 // processRepositories handles the concurrent processing of repositories
-func (p *Processor) processRepositories(ctx context.Context, allRepos []*github.Repository) error {
-	return p.workerPool.ProcessRepositories(ctx, allRepos, func(repo *github.Repository) error {
-		return p.gitManager.ProcessRepository(repo, p.config.OrgName, p.config.Token)
+func (p *Processor) processRepositories(ctx context.Context, allRepos []*provider.Repository) error {
+	return p.workerPool.ProcessRepositories(ctx, allRepos, func(repo *provider.Repository) error {
+		// Resolved per repository, so expiring tokens are refreshed during long runs
+		token, err := p.tokenSource.Token()
+		if err != nil {
+			return fmt.Errorf("error getting token: %w", err)
+		}
+		return p.gitManager.ProcessRepository(repo, token.AccessToken)
 	})
 }
@@ -33,6 +33,13 @@ type ProgressTracker struct {
 	totalRepos      int
 	completedRepos  int
 	failedRepos     int
+	filteredRepos   int
+	cacheHits       int
+	cacheRequests   int
+	scrubbedRemotes int
+	preserved       int
+	branchSwitches  []string
+	localChanges    []string
 	startTime       time.Time
 	progressBar     *progressbar.ProgressBar
 	workerStatuses  map[int]*WorkerStatus
@@ -146,6 +153,58 @@ func (t *ProgressTracker) CompleteRepository(repoName string, success bool, err
 	t.updateDisplay()
 }
 
+// SetFilteredRepos records the number of repositories excluded by filters
+func (t *ProgressTracker) SetFilteredRepos(count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.filteredRepos = count
+}
+
+// SetCacheStats records how many API requests were answered from the HTTP cache
+func (t *ProgressTracker) SetCacheStats(hits, requests int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cacheHits = hits
+	t.cacheRequests = requests
+}
+
+// SetScrubbedRemotes records how many clones had credentials removed from their remote
+func (t *ProgressTracker) SetScrubbedRemotes(count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.scrubbedRemotes = count
+}
+
+// SetPreservedBranches records how many branch tips were saved to backup refs
+// before rewritten history overwrote them
+func (t *ProgressTracker) SetPreservedBranches(count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.preserved = count
+}
+
+// SetBranchSwitches records the clones that followed a change of their
+// default branch, as "repository: old -> new"
+func (t *ProgressTracker) SetBranchSwitches(switches []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.branchSwitches = switches
+}
+
+// SetLocalChanges records the clones whose local changes an update would have
+// overwritten, and what was done with them
+func (t *ProgressTracker) SetLocalChanges(reports []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.localChanges = reports
+}
+
 // GetETA calculates the estimated time of completion
 func (t *ProgressTracker) GetETA() time.Duration {
 	t.mu.RLock()
@@ -182,6 +241,30 @@ func (t *ProgressTracker) PrintSummary() {
 	fmt.Fprintf(t.output, "Total repositories: %d\n", t.totalRepos)
 	fmt.Fprintf(t.output, "Successfully processed: %d\n", t.completedRepos-t.failedRepos)
 	fmt.Fprintf(t.output, "Failed: %d\n", t.failedRepos)
+	if t.filteredRepos > 0 {
+		fmt.Fprintf(t.output, "Filtered out: %d\n", t.filteredRepos)
+	}
+	if t.cacheRequests > 0 {
+		fmt.Fprintf(t.output, "API cache hits: %d of %d requests\n", t.cacheHits, t.cacheRequests)
+	}
+	if t.scrubbedRemotes > 0 {
+		fmt.Fprintf(t.output, "Removed credentials from remotes: %d\n", t.scrubbedRemotes)
+	}
+	if t.preserved > 0 {
+		fmt.Fprintf(t.output, "Saved overwritten branch tips: %d\n", t.preserved)
+	}
+	if len(t.branchSwitches) > 0 {
+		fmt.Fprintf(t.output, "Switched default branch: %d\n", len(t.branchSwitches))
+		for _, s := range t.branchSwitches {
+			fmt.Fprintf(t.output, "  %s\n", s)
+		}
+	}
+	if len(t.localChanges) > 0 {
+		fmt.Fprintf(t.output, "Repositories with local changes: %d\n", len(t.localChanges))
+		for _, r := range t.localChanges {
+			fmt.Fprintf(t.output, "  %s\n", r)
+		}
+	}
 	fmt.Fprintf(t.output, "Total time: %s\n", elapsed.Round(time.Second))
 	if t.completedRepos > 0 {
 		fmt.Fprintf(t.output, "Average time per repo: %s\n", t.avgDuration.Round(time.Second))
@@ -166,22 +166,95 @@ func TestGetRate(t *testing.T) {
 }
 
 func TestPrintSummary(t *testing.T) {
-	var buf bytes.Buffer
-	tracker := NewProgressTracker(10, 4, false, "simple")
-	tracker.output = &buf
-	
-	// Simulate some processing
-	tracker.completedRepos = 8
-	tracker.failedRepos = 2
-	tracker.avgDuration = 5 * time.Second
-	
-	tracker.PrintSummary()
-	
-	output := buf.String()
-	assert.Contains(t, output, "=== Summary ===")
-	assert.Contains(t, output, "Total repositories: 10")
-	assert.Contains(t, output, "Successfully processed: 6")
-	assert.Contains(t, output, "Failed: 2")
+	tests := []struct {
+		name    string
+		total   int
+		setup   func(tracker *ProgressTracker)
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "counts",
+			total: 10,
+			setup: func(tracker *ProgressTracker) {
+				tracker.completedRepos = 8
+				tracker.failedRepos = 2
+				tracker.avgDuration = 5 * time.Second
+			},
+			want: []string{
+				"=== Summary ===",
+				"Total repositories: 10",
+				"Successfully processed: 6",
+				"Failed: 2",
+			},
+			// Optional lines are only printed when their counter is set
+			notWant: []string{
+				"Filtered out",
+				"API cache hits",
+				"Removed credentials",
+				"Switched default branch",
+				"local changes",
+				"Saved overwritten branch tips",
+			},
+		},
+		{
+			name:  "filtered repositories",
+			total: 10,
+			setup: func(tracker *ProgressTracker) { tracker.SetFilteredRepos(3) },
+			want:  []string{"Filtered out: 3"},
+		},
+		{
+			name:  "cache stats",
+			total: 1,
+			setup: func(tracker *ProgressTracker) { tracker.SetCacheStats(4, 5) },
+			want:  []string{"API cache hits: 4 of 5 requests"},
+		},
+		{
+			name:  "scrubbed remotes",
+			total: 1,
+			setup: func(tracker *ProgressTracker) { tracker.SetScrubbedRemotes(2) },
+			want:  []string{"Removed credentials from remotes: 2"},
+		},
+		{
+			name:  "branch switches",
+			total: 1,
+			setup: func(tracker *ProgressTracker) { tracker.SetBranchSwitches([]string{"org/repo: master -> main"}) },
+			want:  []string{"Switched default branch: 1\n  org/repo: master -> main\n"},
+		},
+		{
+			name:  "local changes",
+			total: 1,
+			setup: func(tracker *ProgressTracker) {
+				tracker.SetLocalChanges([]string{"org/repo: skipped (untracked files)"})
+			},
+			want: []string{"Repositories with local changes: 1\n  org/repo: skipped (untracked files)\n"},
+		},
+		{
+			name:  "preserved branches",
+			total: 1,
+			setup: func(tracker *ProgressTracker) { tracker.SetPreservedBranches(3) },
+			want:  []string{"Saved overwritten branch tips: 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tracker := NewProgressTracker(tt.total, 1, false, "simple")
+			tracker.output = &buf
+
+			tt.setup(tracker)
+			tracker.PrintSummary()
+
+			output := buf.String()
+			for _, want := range tt.want {
+				assert.Contains(t, output, want)
+			}
+			for _, notWant := range tt.notWant {
+				assert.NotContains(t, output, notWant)
+			}
+		})
+	}
 }
 
 func TestRecentResultsLimit(t *testing.T) {
@@ -222,4 +295,4 @@ func TestConcurrentAccess(t *testing.T) {
 	
 	assert.Equal(t, 100, tracker.completedRepos)
 	assert.Equal(t, 0, tracker.failedRepos)
-}
\ No newline at end of file
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"time"
+)
+
+// Repository is a provider-neutral description of a hosted Git repository
+type Repository struct {
+	// Owner is the owner path of the repository. Nested namespaces such as
+	// GitLab subgroups are separated by slashes.
+	Owner         string
+	Name          string
+	CloneURL      string
+	SSHURL        string
+	DefaultBranch string
+	// Branch is the branch to check out on clone. The remote HEAD is checked
+	// out when empty.
+	Branch   string
+	Private  bool
+	Archived bool
+	Fork     bool
+	// Visibility is public, private or internal
+	Visibility string
+	Language   string
+	Topics     []string
+	// Size is the repository size in kilobytes, as reported by the provider
+	Size     int
+	PushedAt time.Time
+	// Empty is set when the provider reports that the repository has no commits
+	Empty bool
+	// HeadOID is the commit of the default branch head, when reported by the
+	// provider. It lets updates skip repositories that did not change.
+	HeadOID string
+	// Properties holds custom property values, when loaded by the source.
+	// Multi-select properties have several values.
+	Properties map[string][]string
+}
+
+// FullName returns the owner path and name of the repository
+func (r *Repository) FullName() string {
+	return path.Join(r.Owner, r.Name)
+}
+
+// Source lists the repositories to process from a hosting provider
+type Source interface {
+	ListRepositories(ctx context.Context) ([]*Repository, error)
+}
+
+// PropertyLoader is implemented by sources that can attach custom property
+// values to the repositories they listed
+type PropertyLoader interface {
+	LoadProperties(ctx context.Context, repos []*Repository) error
+}
+
+// RateLimitReporter is implemented by sources whose API is rate limited. The
+// remaining request budget is logged at the start and end of a run.
+type RateLimitReporter interface {
+	LogRateLimit(ctx context.Context, stage string)
+}
+
+// ReplaceHost replaces the host of a clone URL. The URL is returned unchanged
+// when host is empty or the URL cannot be parsed.
+func ReplaceHost(cloneURL, host string) string {
+	if host == "" {
+		return cloneURL
+	}
+
+	u, err := url.Parse(cloneURL)
+	if err != nil || u.Host == "" {
+		return cloneURL
+	}
+	u.Host = host
+	return u.String()
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_FullName(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     Repository
+		expected string
+	}{
+		{
+			name:     "single owner",
+			repo:     Repository{Owner: "org", Name: "repo"},
+			expected: "org/repo",
+		},
+		{
+			name:     "nested owner",
+			repo:     Repository{Owner: "group/subgroup", Name: "project"},
+			expected: "group/subgroup/project",
+		},
+		{
+			name:     "no owner",
+			repo:     Repository{Name: "repo"},
+			expected: "repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.repo.FullName())
+		})
+	}
+}
+
+func TestReplaceHost(t *testing.T) {
+	assert.Equal(t, "https://github.com/org/repo.git", ReplaceHost("https://github.com/org/repo.git", ""))
+	assert.Equal(t, "https://git.example.com/org/repo.git", ReplaceHost("https://github.com/org/repo.git", "git.example.com"))
+	assert.Equal(t, "not a url", ReplaceHost("not a url", "git.example.com"))
+}
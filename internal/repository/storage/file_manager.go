@@ -1,13 +1,21 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 
-	"github.com/google/go-github/v60/github"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
 )
 
 // FileManager handles file system operations
@@ -23,7 +31,7 @@ func NewFileManager(cfg *config.Config) *FileManager {
 }
 
 // SaveRepositoryList saves the list of repositories to a file
-func (f *FileManager) SaveRepositoryList(allRepos []*github.Repository, orgName string) error {
+func (f *FileManager) SaveRepositoryList(allRepos []*provider.Repository) error {
 	repoListPath := filepath.Join(f.config.OutputDir, "repository_list.txt")
 	file, err := os.Create(repoListPath)
 	if err != nil {
@@ -32,8 +40,11 @@ func (f *FileManager) SaveRepositoryList(allRepos []*github.Repository, orgName
 	defer file.Close()
 
 	for _, repo := range allRepos {
-		httpsURL := fmt.Sprintf("https://github.com/%s/%s.git", orgName, *repo.Name)
-		if _, err := file.WriteString(fmt.Sprintf("%s - %s\n", *repo.Name, httpsURL)); err != nil {
+		entry := fmt.Sprintf("%s - %s", filepath.ToSlash(f.config.RepositoryPath(repo.Owner, repo.Name)), repo.CloneURL)
+		if repo.Visibility != "" {
+			entry += fmt.Sprintf(" (%s)", repo.Visibility)
+		}
+		if _, err := file.WriteString(entry + "\n"); err != nil {
 			return fmt.Errorf("error writing to repository list file: %w", err)
 		}
 	}
@@ -43,22 +54,73 @@ func (f *FileManager) SaveRepositoryList(allRepos []*github.Repository, orgName
 }
 
 // CleanupOldRepositories removes repositories that no longer exist
-func (f *FileManager) CleanupOldRepositories(allRepos []*github.Repository) error {
+func (f *FileManager) CleanupOldRepositories(allRepos []*provider.Repository) error {
+	if f.config.Layout == config.LayoutOwner {
+		return f.cleanupOwnerDirectories(allRepos)
+	}
+
 	validRepos := make(map[string]bool)
 	for _, repo := range allRepos {
-		validRepos[*repo.Name] = true
+		validRepos[repo.Name] = true
 	}
 
-	entries, err := os.ReadDir(f.config.OutputDir)
+	return f.cleanupDirectory(f.config.OutputDir, validRepos)
+}
+
+// cleanupOwnerDirectories removes stale repositories inside the directory of
+// every owner in the current run. Directories of other owners are left alone.
+// Nested owners such as GitLab subgroups keep their parent directories valid.
+func (f *FileManager) cleanupOwnerDirectories(allRepos []*provider.Repository) error {
+	validRepos := make(map[string]map[string]bool)
+	addValid := func(owner, name string) {
+		if validRepos[owner] == nil {
+			validRepos[owner] = make(map[string]bool)
+		}
+		validRepos[owner][name] = true
+	}
+
+	for _, repo := range allRepos {
+		addValid(repo.Owner, repo.Name)
+		for owner := repo.Owner; strings.Contains(owner, "/"); owner = path.Dir(owner) {
+			addValid(path.Dir(owner), path.Base(owner))
+		}
+	}
+
+	// Repositories without an owner, as from a manifest, live in the output
+	// directory itself, next to the directories of the other owners
+	if validRepos[""] != nil {
+		for _, repo := range allRepos {
+			if repo.Owner != "" {
+				addValid("", strings.SplitN(repo.Owner, "/", 2)[0])
+			}
+		}
+	}
+
+	for owner, names := range validRepos {
+		ownerDir := filepath.Join(f.config.OutputDir, filepath.FromSlash(owner))
+		if _, err := os.Stat(ownerDir); os.IsNotExist(err) {
+			continue
+		}
+		if err := f.cleanupDirectory(ownerDir, names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupDirectory removes every subdirectory of dir that is not a valid repository name
+func (f *FileManager) cleanupDirectory(dir string, validRepos map[string]bool) error {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return fmt.Errorf("error reading output directory: %w", err)
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() && entry.Name() != ".git" {
+		if entry.IsDir() && entry.Name() != ".git" && entry.Name() != config.StateDirName {
 			if !validRepos[entry.Name()] {
-				fullPath := filepath.Join(f.config.OutputDir, entry.Name())
-				slog.Info("Removing repository", "name", entry.Name(), "reason", "no longer exists in organization")
+				fullPath := filepath.Join(dir, entry.Name())
+				slog.Info("Removing repository", "path", fullPath, "reason", "no longer exists in organization")
 				if err := os.RemoveAll(fullPath); err != nil {
 					return fmt.Errorf("error removing directory %s: %w", fullPath, err)
 				}
@@ -67,4 +129,163 @@ func (f *FileManager) CleanupOldRepositories(allRepos []*github.Repository) erro
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// CleanupIncompleteClones removes the staging directories of runs on this
+// host that were interrupted, and clones in the output directory that were
+// initialised but never received anything, so that they are cloned again.
+// Clones that cannot be opened are reported and left alone.
+func (f *FileManager) CleanupIncompleteClones() error {
+	if err := f.cleanupStaging(); err != nil {
+		return err
+	}
+
+	clones, err := FindClones(f.config)
+	if err != nil {
+		return err
+	}
+	for _, clonePath := range clones {
+		incomplete, err := isIncomplete(clonePath)
+		if err != nil {
+			slog.Warn("Failed to check repository", "path", clonePath, "error", err)
+			continue
+		}
+		if !incomplete {
+			continue
+		}
+		slog.Info("Removing repository", "path", clonePath, "reason", "incomplete clone")
+		if err := os.RemoveAll(clonePath); err != nil {
+			return fmt.Errorf("error removing directory %s: %w", clonePath, err)
+		}
+	}
+	return nil
+}
+
+// cleanupStaging removes the staging directories of processes on this host
+// that are no longer running. This process has not cloned anything yet, so a
+// staging directory under its own name was left by an earlier process with
+// the same ID. Staging directories of other hosts sharing the output
+// directory are left alone.
+func (f *FileManager) cleanupStaging() error {
+	root := filepath.Dir(f.config.StagingDir())
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading staging directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	own := filepath.Base(f.config.StagingDir())
+	for _, entry := range entries {
+		stagingPath := filepath.Join(root, entry.Name())
+		if entry.Name() != own {
+			owner, pid, ok := parseStagingName(entry.Name())
+			if !ok || owner != host {
+				slog.Debug("Keeping staging directory of another host", "path", stagingPath)
+				continue
+			}
+			if processRunning(pid) {
+				slog.Debug("Keeping staging directory of a running process", "path", stagingPath)
+				continue
+			}
+		}
+		slog.Info("Removing interrupted clones", "path", stagingPath)
+		if err := os.RemoveAll(stagingPath); err != nil {
+			return fmt.Errorf("error removing staging directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseStagingName returns the host and process ID in the name of a staging
+// directory
+func parseStagingName(name string) (string, int, bool) {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return "", 0, false
+	}
+	pid, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return name[:i], pid, true
+}
+
+// processRunning reports whether a process with the ID is running. A process
+// that cannot be signalled, such as one of another user, is running.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return !errors.Is(process.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+// FindClones returns the paths of the clones and mirrors in the output
+// directory. The state directory is not searched.
+func FindClones(cfg *config.Config) ([]string, error) {
+	var clones []string
+	err := filepath.WalkDir(cfg.OutputDir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == cfg.OutputDir {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p == cfg.StatePath() {
+			return filepath.SkipDir
+		}
+		if isClone(p) {
+			clones = append(clones, p)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error searching output directory: %w", err)
+	}
+	return clones, nil
+}
+
+// isClone reports whether dir is a clone with a working tree or a bare mirror
+func isClone(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+	_, headErr := os.Stat(filepath.Join(dir, "HEAD"))
+	_, objectsErr := os.Stat(filepath.Join(dir, "objects"))
+	return headErr == nil && objectsErr == nil
+}
+
+// isIncomplete reports whether a clone was initialised and never received
+// anything: it has no origin remote, no commit checked out and no files in its
+// worktree, so removing it loses nothing. Clones of empty repositories have a
+// remote and are kept.
+func isIncomplete(dir string) (bool, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	if _, err := repo.Remote("origin"); err == nil {
+		return false, nil
+	}
+	if _, err := repo.Reference(plumbing.HEAD, true); !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.Name() != ".git" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -1,17 +1,20 @@
 package storage
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
-	"github.com/google/go-github/v60/github"
+	"github.com/go-git/go-git/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/truemilk/ghloner/internal/config"
+	"github.com/truemilk/ghloner/internal/repository/provider"
 	"github.com/truemilk/ghloner/test/fixtures"
+	"github.com/truemilk/ghloner/test/helpers"
 )
 
 func TestNewFileManager(t *testing.T) {
@@ -68,10 +71,10 @@ func TestSaveRepositoryList(t *testing.T) {
 			fm := NewFileManager(cfg)
 
 			// Create test repositories
-			repos := fixtures.CreateTestRepositories(tt.repoCount)
+			repos := fixtures.CreateRepositories(tt.repoCount)
 
 			// Save repository list
-			err := fm.SaveRepositoryList(repos, tt.orgName)
+			err := fm.SaveRepositoryList(repos)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -95,9 +98,9 @@ func TestSaveRepositoryList(t *testing.T) {
 					lineCount := 0
 					for _, line := range strings.Split(lines, "\n") {
 						if strings.TrimSpace(line) != "" {
+							// Verify line format uses the clone URL from the API
+							assert.Contains(t, line, " - "+repos[lineCount].CloneURL)
 							lineCount++
-							// Verify line format
-							assert.Contains(t, line, " - https://github.com/"+tt.orgName+"/")
 						}
 					}
 					assert.Equal(t, tt.repoCount, lineCount)
@@ -125,10 +128,10 @@ func TestSaveRepositoryList_FilePermissionError(t *testing.T) {
 	}
 	fm := NewFileManager(cfg)
 
-	repos := fixtures.CreateTestRepositories(1)
+	repos := fixtures.CreateRepositories(1)
 
 	// Should fail due to permission error
-	err = fm.SaveRepositoryList(repos, "testorg")
+	err = fm.SaveRepositoryList(repos)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "error creating repository list file")
 }
@@ -167,10 +170,10 @@ func TestCleanupOldRepositories(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create repository objects for current repos
-	repos := make([]*github.Repository, len(currentRepos))
+	repos := make([]*provider.Repository, len(currentRepos))
 	for i, name := range currentRepos {
-		repos[i] = &github.Repository{
-			Name: github.String(name),
+		repos[i] = &provider.Repository{
+			Name: name,
 		}
 	}
 
@@ -200,7 +203,7 @@ func TestCleanupOldRepositories_EmptyDirectory(t *testing.T) {
 	fm := NewFileManager(cfg)
 
 	// No repositories to keep
-	repos := []*github.Repository{}
+	repos := []*provider.Repository{}
 
 	// Should not crash on empty directory
 	err := fm.CleanupOldRepositories(repos)
@@ -234,7 +237,7 @@ func TestCleanupOldRepositories_PermissionError(t *testing.T) {
 	defer os.Chmod(tempDir, 0755) // Restore permissions
 
 	// Try to cleanup (should get error)
-	repos := []*github.Repository{}
+	repos := []*provider.Repository{}
 	err = fm.CleanupOldRepositories(repos)
 	
 	// Should get permission error
@@ -250,31 +253,32 @@ func TestSaveRepositoryList_TextFormat(t *testing.T) {
 	fm := NewFileManager(cfg)
 
 	// Create repos with various field types
-	repos := []*github.Repository{
+	repos := []*provider.Repository{
 		{
-			ID:          github.Int64(123),
-			Name:        github.String("test-repo"),
-			FullName:    github.String("org/test-repo"),
-			Description: github.String("A test repository"),
-			Private:     github.Bool(false),
-			Fork:        github.Bool(false),
-			CreatedAt:   &github.Timestamp{Time: time.Now()},
-			UpdatedAt:   &github.Timestamp{Time: time.Now()},
-			Size:        github.Int(1024),
-			Language:    github.String("Go"),
-			HasIssues:   github.Bool(true),
+			Owner:         "testorg",
+			Name:          "test-repo",
+			CloneURL:      "https://github.com/testorg/test-repo.git",
+			SSHURL:        "git@github.com:testorg/test-repo.git",
+			DefaultBranch: "main",
 		},
 		{
-			ID:       github.Int64(456),
-			Name:     github.String("another-repo"),
-			FullName: github.String("org/another-repo"),
-			Private:  github.Bool(true),
-			Fork:     github.Bool(true),
+			Owner:    "testorg",
+			Name:     "another-repo",
+			CloneURL: "https://github.com/testorg/another-repo.git",
+			Private:  true,
+			Fork:     true,
+		},
+		{
+			Owner:      "testorg",
+			Name:       "internal-repo",
+			CloneURL:   "https://github.com/testorg/internal-repo.git",
+			Private:    true,
+			Visibility: "internal",
 		},
 	}
 
 	// Save and verify
-	err := fm.SaveRepositoryList(repos, "testorg")
+	err := fm.SaveRepositoryList(repos)
 	require.NoError(t, err)
 
 	// Read back and verify
@@ -286,8 +290,222 @@ func TestSaveRepositoryList_TextFormat(t *testing.T) {
 	// Verify content
 	assert.Contains(t, content, "test-repo - https://github.com/testorg/test-repo.git")
 	assert.Contains(t, content, "another-repo - https://github.com/testorg/another-repo.git")
+	assert.Contains(t, content, "internal-repo - https://github.com/testorg/internal-repo.git (internal)")
 	
 	// Verify line count
 	lines := strings.Split(strings.TrimSpace(content), "\n")
-	assert.Len(t, lines, 2)
-}
\ No newline at end of file
+	assert.Len(t, lines, 3)
+}
+
+func TestCleanupOldRepositories_OwnerLayout(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+		Layout:    config.LayoutOwner,
+	}
+	fm := NewFileManager(cfg)
+
+	dirs := []string{
+		"orga/shared",
+		"orga/stale",
+		"orgb/shared",
+		"orgb/other",
+		"unrelated/repo",
+	}
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+	}
+
+	repos := []*provider.Repository{
+		{Owner: "orga", Name: "shared"},
+		{Owner: "orgb", Name: "shared"},
+		{Owner: "orgb", Name: "other"},
+	}
+
+	err := fm.CleanupOldRepositories(repos)
+	require.NoError(t, err)
+
+	assert.DirExists(t, filepath.Join(tempDir, "orga", "shared"))
+	assert.DirExists(t, filepath.Join(tempDir, "orgb", "shared"))
+	assert.DirExists(t, filepath.Join(tempDir, "orgb", "other"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "orga", "stale"))
+
+	// Owners outside the current run are not touched
+	assert.DirExists(t, filepath.Join(tempDir, "unrelated", "repo"))
+}
+
+func TestSaveRepositoryList_OwnerLayout(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+		Layout:    config.LayoutOwner,
+	}
+	fm := NewFileManager(cfg)
+
+	repos := []*provider.Repository{
+		{Owner: "orga", Name: "shared", CloneURL: "https://github.com/orga/shared.git"},
+		{Owner: "orgb", Name: "shared", CloneURL: "https://github.com/orgb/shared.git"},
+	}
+
+	err := fm.SaveRepositoryList(repos)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "repository_list.txt"))
+	require.NoError(t, err)
+
+	content := string(data)
+	assert.Contains(t, content, "orga/shared - https://github.com/orga/shared.git")
+	assert.Contains(t, content, "orgb/shared - https://github.com/orgb/shared.git")
+}
+
+func TestCleanupOldRepositories_NestedOwners(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+		Layout:    config.LayoutOwner,
+	}
+	fm := NewFileManager(cfg)
+
+	dirs := []string{
+		"group/project",
+		"group/stale-project",
+		"group/subgroup/project",
+		"group/subgroup/stale-project",
+	}
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+	}
+
+	repos := []*provider.Repository{
+		{Owner: "group", Name: "project"},
+		{Owner: "group/subgroup", Name: "project"},
+	}
+
+	err := fm.CleanupOldRepositories(repos)
+	require.NoError(t, err)
+
+	assert.DirExists(t, filepath.Join(tempDir, "group", "project"))
+	assert.DirExists(t, filepath.Join(tempDir, "group", "subgroup", "project"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "group", "stale-project"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "group", "subgroup", "stale-project"))
+}
+
+func TestCleanupOldRepositories_RootOwner(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+		Layout:    config.LayoutOwner,
+	}
+	fm := NewFileManager(cfg)
+
+	dirs := []string{
+		"tools",
+		"stale-tool",
+		"services/backend/api",
+		"services/backend/stale-api",
+	}
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+	}
+
+	repos := []*provider.Repository{
+		{Name: "tools"},
+		{Owner: "services/backend", Name: "api"},
+	}
+
+	err := fm.CleanupOldRepositories(repos)
+	require.NoError(t, err)
+
+	assert.DirExists(t, filepath.Join(tempDir, "tools"))
+	assert.DirExists(t, filepath.Join(tempDir, "services", "backend", "api"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "stale-tool"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "services", "backend", "stale-api"))
+}
+
+func TestCleanupOldRepositories_KeepsStateDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+	}
+	fm := NewFileManager(cfg)
+
+	require.NoError(t, os.MkdirAll(cfg.StatePath("http-cache"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "old-repo"), 0755))
+
+	err := fm.CleanupOldRepositories([]*provider.Repository{{Name: "repo1"}})
+	require.NoError(t, err)
+
+	assert.DirExists(t, cfg.StatePath("http-cache"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "old-repo"))
+}
+
+func TestCleanupIncompleteClones(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+	}
+	fm := NewFileManager(cfg)
+
+	// Complete clones, including a clone of an empty repository
+	helpers.CreateTestRepo(t, filepath.Join(tempDir, "org", "repo"))
+	helpers.CreateEmptyClone(t, filepath.Join(tempDir, "org", "empty"), "https://github.com/org/empty.git")
+	// A clone interrupted before its remote was added
+	_, err := git.PlainInit(filepath.Join(tempDir, "org", "initialised"), false)
+	require.NoError(t, err)
+	// Checkouts that are not known to be incomplete: one that cannot be
+	// opened, and one without a remote that has files
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "broken", ".git"), 0755))
+	_, err = git.PlainInit(filepath.Join(tempDir, "org", "scratch"), false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "org", "scratch", "notes.txt"), []byte("notes\n"), 0644))
+
+	// Staging directories of an interrupted run, of a running process, of
+	// another host and of an earlier process with the ID of this one
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	stagingRoot := filepath.Dir(cfg.StagingDir())
+	interrupted := filepath.Join(stagingRoot, fmt.Sprintf("%s-%d", host, math.MaxInt32), "repo-123")
+	running := filepath.Join(stagingRoot, fmt.Sprintf("%s-%d", host, os.Getppid()), "repo-456")
+	otherHost := filepath.Join(stagingRoot, fmt.Sprintf("%s-other-%d", host, os.Getppid()), "repo-789")
+	own := filepath.Join(cfg.StagingDir(), "repo-000")
+	for _, dir := range []string{interrupted, running, otherHost, own} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	}
+	require.NoError(t, os.MkdirAll(cfg.StatePath("http-cache"), 0755))
+
+	require.NoError(t, fm.CleanupIncompleteClones())
+
+	assert.DirExists(t, filepath.Join(tempDir, "org", "repo"))
+	assert.DirExists(t, filepath.Join(tempDir, "org", "empty"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "org", "initialised"))
+	assert.DirExists(t, filepath.Join(tempDir, "broken"))
+	assert.DirExists(t, filepath.Join(tempDir, "org", "scratch"))
+	assert.NoDirExists(t, filepath.Dir(interrupted))
+	assert.DirExists(t, running)
+	assert.DirExists(t, otherHost)
+	assert.NoDirExists(t, cfg.StagingDir())
+	assert.DirExists(t, cfg.StatePath("http-cache"))
+}
+
+func TestFindClones(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir: tempDir,
+	}
+
+	helpers.CreateTestRepo(t, filepath.Join(tempDir, "org", "repo"))
+	helpers.CreateBareRepo(t, filepath.Join(tempDir, "org", "mirror"))
+	helpers.CreateTestRepo(t, cfg.StatePath("quarantine", "repo"))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "org", "notes"), 0755))
+
+	clones, err := FindClones(cfg)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(tempDir, "org", "repo"),
+		filepath.Join(tempDir, "org", "mirror"),
+	}, clones)
+
+	clones, err = FindClones(&config.Config{OutputDir: filepath.Join(tempDir, "missing")})
+	require.NoError(t, err)
+	assert.Empty(t, clones)
+}
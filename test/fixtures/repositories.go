@@ -0,0 +1,25 @@
+package fixtures
+
+import (
+	"github.com/truemilk/ghloner/internal/repository/provider"
+)
+
+// CreateRepository creates a test provider-neutral repository
+func CreateRepository(name string) *provider.Repository {
+	return &provider.Repository{
+		Owner:         "testorg",
+		Name:          name,
+		CloneURL:      "https://github.com/testorg/" + name + ".git",
+		SSHURL:        "git@github.com:testorg/" + name + ".git",
+		DefaultBranch: "main",
+	}
+}
+
+// CreateRepositories creates a list of test provider-neutral repositories
+func CreateRepositories(count int) []*provider.Repository {
+	repos := make([]*provider.Repository, count)
+	for i := 0; i < count; i++ {
+		repos[i] = CreateRepository("test-repo-" + string(rune('a'+i)))
+	}
+	return repos
+}
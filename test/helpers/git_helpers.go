@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +63,23 @@ func CreateBareRepo(t *testing.T, path string) *git.Repository {
 	return repo
 }
 
+// CreateEmptyClone creates a clone of an empty repository, as git clone does:
+// a repository with an origin remote and an unborn HEAD
+func CreateEmptyClone(t *testing.T, path, remoteURL string) *git.Repository {
+	t.Helper()
+
+	repo, err := git.PlainInit(path, false)
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name:  "origin",
+		URLs:  []string{remoteURL},
+		Fetch: []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+	})
+	require.NoError(t, err)
+
+	return repo
+}
+
 // SimulateNonFastForward simulates a non-fast-forward scenario in a repository
 func SimulateNonFastForward(t *testing.T, remotePath, localPath string) {
 	t.Helper()
@@ -121,6 +139,30 @@ func SimulateNonFastForward(t *testing.T, remotePath, localPath string) {
 	require.NoError(t, err)
 }
 
+// CommitFile writes a file to the worktree of a repository and commits it
+func CommitFile(t *testing.T, repo *git.Repository, name, content string) plumbing.Hash {
+	t.Helper()
+
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(w.Filesystem.Root(), name), []byte(content), 0644)
+	require.NoError(t, err)
+
+	_, err = w.Add(name)
+	require.NoError(t, err)
+
+	hash, err := w.Commit("Update "+name, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "Test User",
+			Email: "test@example.com",
+		},
+	})
+	require.NoError(t, err)
+
+	return hash
+}
+
 // AddRemote adds a remote to a repository
 func AddRemote(t *testing.T, repo *git.Repository, name, url string) {
 	t.Helper()
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"encoding/pem"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// StartHTTPSGitServer starts a TLS server that serves the repositories below
+// root with git http-backend. Its certificate is signed by a test CA, see
+// WriteCABundle. The test is skipped without a git installation.
+func StartHTTPSGitServer(t *testing.T, root string) *httptest.Server {
+	t.Helper()
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	server := httptest.NewTLSServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env: []string{
+			"GIT_PROJECT_ROOT=" + root,
+			"GIT_HTTP_EXPORT_ALL=1",
+		},
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+// WriteCABundle writes the certificate of a TLS test server as a PEM CA bundle
+func WriteCABundle(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
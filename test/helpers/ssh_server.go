@@ -0,0 +1,186 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHGitServer is an in-process SSH server that serves git-upload-pack for
+// repositories on the local filesystem. Clients must authenticate as User
+// with the authorized key.
+type SSHGitServer struct {
+	Addr    string
+	User    string
+	HostKey ssh.PublicKey
+}
+
+// StartSSHGitServer starts an SSH git server for the git user on a random
+// local port. It is stopped when the test ends. The test is skipped without a
+// git installation.
+func StartSSHGitServer(t *testing.T, authorizedKey ssh.PublicKey) *SSHGitServer {
+	t.Helper()
+	return StartSSHGitServerForUser(t, authorizedKey, "git")
+}
+
+// StartSSHGitServerForUser starts an SSH git server that only accepts user
+func StartSSHGitServerForUser(t *testing.T, authorizedKey ssh.PublicKey, user string) *SSHGitServer {
+	t.Helper()
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	hostSigner := NewSSHSigner(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() != user {
+				return nil, fmt.Errorf("unknown user %s", conn.User())
+			}
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unauthorized key for %s", conn.User())
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSHConn(conn, config, gitPath)
+		}
+	}()
+
+	return &SSHGitServer{
+		Addr:    listener.Addr().String(),
+		User:    user,
+		HostKey: hostSigner.PublicKey(),
+	}
+}
+
+// URL returns the SSH URL of a repository on the local filesystem
+func (s *SSHGitServer) URL(repoPath string) string {
+	return "ssh://" + s.User + "@" + s.Addr + filepath.ToSlash(repoPath)
+}
+
+// KnownHostsLine returns the known_hosts entry of the server
+func (s *SSHGitServer) KnownHostsLine() string {
+	return knownhosts.Line([]string{knownhosts.Normalize(s.Addr)}, s.HostKey)
+}
+
+// NewSSHSigner generates an Ed25519 key
+func NewSSHSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+func serveSSHConn(conn net.Conn, config *ssh.ServerConfig, gitPath string) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go serveSSHSession(channel, requests, gitPath)
+	}
+}
+
+func serveSSHSession(channel ssh.Channel, requests <-chan *ssh.Request, gitPath string) {
+	for req := range requests {
+		switch req.Type {
+		case "env":
+			req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			go runGitCommand(channel, payload.Command, gitPath)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// runGitCommand runs a git-upload-pack command, such as
+// git-upload-pack '/path/to/repo', with git upload-pack and reports its exit
+// status
+func runGitCommand(channel ssh.Channel, command, gitPath string) {
+	defer channel.Close()
+
+	name, arg, _ := strings.Cut(command, " ")
+	status := uint32(1)
+	if name == "git-upload-pack" {
+		status = runWithChannel(channel, exec.Command(gitPath, "upload-pack", strings.Trim(arg, "'")))
+	} else {
+		fmt.Fprintf(channel.Stderr(), "unsupported command: %s\n", name)
+	}
+
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
+
+// runWithChannel connects a command to the channel and returns its exit status.
+// Standard input is copied separately, as the client may keep it open until
+// it receives the exit status.
+func runWithChannel(channel ssh.Channel, cmd *exec.Cmd) uint32 {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 1
+	}
+	cmd.Stdout = channel
+	cmd.Stderr = channel.Stderr()
+
+	if err := cmd.Start(); err != nil {
+		return 1
+	}
+	go func() {
+		io.Copy(stdin, channel)
+		stdin.Close()
+	}()
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); errors.As(err, &exitErr) {
+		return uint32(exitErr.ExitCode())
+	} else if err != nil {
+		return 1
+	}
+	return 0
+}